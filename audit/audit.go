@@ -0,0 +1,294 @@
+// Package audit ведет журнал взаимодействий с Оплати для фискального аудита. В журнал записываются все запросы к API
+// и ответы на них (с замененными паролями) и все уведомления с проверенной подписью вместе с Server-Sign.
+//
+// Каждая запись содержит хеш предыдущей записи и собственный хеш, поэтому изменение, удаление или перестановка
+// записей обнаруживается с помощью Verify. Журнал хранится в Backend: в файле (NewFile) или в БД (NewSQL).
+//
+// Без ключа цепочку может пересчитать любой, у кого есть доступ к журналу, поэтому для защиты от подделки
+// используйте WithKey (хеши вычисляются как HMAC-SHA256). Удаление последних записей не нарушает цепочку: чтобы его
+// обнаружить, периодически сохраняйте Log.Head вне журнала и передавайте его в Verify через WithAnchor.
+//
+//	backend, err := audit.NewFile("oplati-audit.jsonl")
+//	// ...
+//	log, err := audit.New(ctx, backend, audit.WithErrorHandler(func(err error) { slog.Error(err.Error()) }))
+//	// ...
+//	oplatiClient := oacquiring.NewClient(baseUrl, regNum, password, log.ClientOpt())
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, log.NotificationHandler(&Handler{}))
+//
+// Журнал должен вести один процесс: записи разных процессов в один Backend образуют несогласованную цепочку.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// KindRequest - запрос к API Оплати и ответ на него
+	KindRequest = "request"
+	// KindNotification - уведомление от сервера Оплати с проверенной подписью
+	KindNotification = "notification"
+)
+
+type (
+	// Record - запись журнала
+	Record struct {
+		Seq                uint64      `json:"seq"`                          // Порядковый номер, начиная с 1
+		Time               time.Time   `json:"time"`                         // Время записи (UTC)
+		Kind               string      `json:"kind"`                         // KindRequest или KindNotification
+		Operation          string      `json:"operation,omitempty"`          // Операция Client (oacquiring.Operation)
+		Method             string      `json:"method,omitempty"`             // HTTP метод запроса
+		URL                string      `json:"url,omitempty"`                // URL запроса
+		RequestHeader      http.Header `json:"requestHeader,omitempty"`      // Заголовки запроса или уведомления
+		RequestBody        []byte      `json:"requestBody,omitempty"`        // Тело запроса или уведомления
+		StatusCode         int         `json:"statusCode,omitempty"`         // HTTP код ответа
+		ResponseHeader     http.Header `json:"responseHeader,omitempty"`     // Заголовки ответа
+		ResponseBody       []byte      `json:"responseBody,omitempty"`       // Тело ответа
+		ResponseBodySize   int64       `json:"responseBodySize,omitempty"`   // Размер тела ответа больше 1 МБ
+		ResponseBodySHA256 string      `json:"responseBodySha256,omitempty"` // sha256 тела ответа больше 1 МБ в hex
+		Error              string      `json:"error,omitempty"`              // Ошибка выполнения запроса
+		Signature          string      `json:"signature,omitempty"`          // Server-Sign уведомления
+		PrevHash           string      `json:"prevHash"`                     // Hash предыдущей записи, пустой для первой
+		Hash               string      `json:"hash,omitempty"`               // sha256 (или HMAC-SHA256) записи без Hash в hex
+	}
+
+	// Backend - хранилище записей журнала. Реализации: File, SQL.
+	Backend interface {
+		// Append добавляет запись в конец журнала
+		Append(ctx context.Context, record Record) error
+		// Last возвращает последнюю запись журнала. Для пустого журнала возвращает false.
+		Last(ctx context.Context) (Record, bool, error)
+		// Records возвращает все записи журнала в порядке добавления
+		Records(ctx context.Context) iter.Seq2[Record, error]
+	}
+
+	// Log - журнал аудита. Для инициализации используйте New.
+	Log struct {
+		mu       sync.Mutex
+		backend  Backend
+		lastSeq  uint64
+		lastHash string
+
+		key     []byte
+		redact  []string
+		onError func(error)
+	}
+
+	// Opt - дополнительные параметры Log
+	Opt func(*Log)
+
+	// verifyOptions - параметры Verify
+	verifyOptions struct {
+		key        []byte
+		anchored   bool
+		anchorSeq  uint64
+		anchorHash string
+	}
+
+	// VerifyOpt - дополнительные параметры Verify
+	VerifyOpt func(*verifyOptions)
+
+	// VerificationError - нарушение целостности журнала, обнаруженное Verify
+	VerificationError struct {
+		Seq    uint64 // Номер записи, на которой обнаружено нарушение
+		Reason string // Описание нарушения
+	}
+)
+
+// WithErrorHandler - функция, вызываемая, если запись о запросе к API не удалось сохранить. Запрос при этом не
+// прерывается.
+func WithErrorHandler(onError func(error)) Opt {
+	return func(l *Log) {
+		l.onError = onError
+	}
+}
+
+// WithRedactedHeaders - заголовки, значения которых заменяются на oacquiring.RedactedValue. По умолчанию
+// oacquiring.DefaultRedactedHeaders.
+func WithRedactedHeaders(names ...string) Opt {
+	return func(l *Log) {
+		l.redact = names
+	}
+}
+
+// WithKey - ключ HMAC-SHA256 для хешей записей. Без ключа используется sha256, и цепочку может пересчитать любой, у
+// кого есть доступ к журналу. Ключ должен храниться отдельно от журнала и передаваться в Verify через WithVerifyKey.
+func WithKey(key []byte) Opt {
+	return func(l *Log) {
+		l.key = key
+	}
+}
+
+// WithVerifyKey - ключ HMAC-SHA256, с которым велся журнал (см. WithKey)
+func WithVerifyKey(key []byte) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.key = key
+	}
+}
+
+// WithAnchor - номер и хеш записи, сохраненные ранее вне журнала (см. Log.Head). Verify возвращает
+// *VerificationError, если такой записи нет или ее хеш отличается, что позволяет обнаружить удаление последних
+// записей.
+func WithAnchor(seq uint64, hash string) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.anchored, o.anchorSeq, o.anchorHash = true, seq, hash
+	}
+}
+
+// New возвращает Log, продолжающий цепочку записей backend
+func New(ctx context.Context, backend Backend, opts ...Opt) (*Log, error) {
+	l := &Log{backend: backend}
+
+	last, ok, err := backend.Last(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("last record reading failed: %w", err)
+	}
+	if ok {
+		l.lastSeq, l.lastHash = last.Seq, last.Hash
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l, nil
+}
+
+// Append присваивает записи номер, время и хеши, добавляет ее в журнал и возвращает добавленную запись
+func (l *Log) Append(ctx context.Context, record Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Seq = l.lastSeq + 1
+	record.Time = time.Now().UTC()
+	record.PrevHash = l.lastHash
+
+	hash, err := record.computeHash(l.key)
+	if err != nil {
+		return Record{}, err
+	}
+	record.Hash = hash
+
+	err = l.backend.Append(ctx, record)
+	if err != nil {
+		return Record{}, fmt.Errorf("audit record %d appending failed: %w", record.Seq, err)
+	}
+
+	l.lastSeq, l.lastHash = record.Seq, record.Hash
+	return record, nil
+}
+
+// Head возвращает номер и хеш последней записи журнала (0 и пустую строку для пустого журнала). Сохраненные вне
+// журнала значения передаются в Verify через WithAnchor.
+func (l *Log) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lastSeq, l.lastHash
+}
+
+// ComputeHash возвращает хеш записи: sha256 от json записи с пустым Hash
+func (r Record) ComputeHash() (string, error) {
+	return r.computeHash(nil)
+}
+
+// ComputeHMAC возвращает хеш записи для журнала с ключом (см. WithKey): HMAC-SHA256 от json записи с пустым Hash
+func (r Record) ComputeHMAC(key []byte) (string, error) {
+	return r.computeHash(key)
+}
+
+func (r Record) computeHash(key []byte) (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("audit record encoding failed: %w", err)
+	}
+
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify проверяет целостность журнала: номера записей идут подряд с 1, каждая запись ссылается на хеш предыдущей и
+// ее хеш совпадает с содержимым. Возвращает количество проверенных записей. При нарушении возвращает
+// *VerificationError.
+//   - opts - Дополнительные настройки: WithVerifyKey, WithAnchor
+func Verify(ctx context.Context, backend Backend, opts ...VerifyOpt) (int, error) {
+	var o verifyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		count    int
+		prevSeq  uint64
+		prevHash string
+	)
+
+	for record, err := range backend.Records(ctx) {
+		if err != nil {
+			return count, err
+		}
+
+		switch {
+		case record.Seq != prevSeq+1:
+			return count, &VerificationError{Seq: record.Seq,
+				Reason: fmt.Sprintf("expected record %d: records are missing or reordered", prevSeq+1)}
+		case record.PrevHash != prevHash:
+			return count, &VerificationError{Seq: record.Seq,
+				Reason: "previous hash does not match: the previous record was changed or removed"}
+		}
+
+		hash, err := record.computeHash(o.key)
+		if err != nil {
+			return count, err
+		}
+		if hash != record.Hash {
+			return count, &VerificationError{Seq: record.Seq, Reason: "hash does not match: the record was changed"}
+		}
+		if o.anchored && record.Seq == o.anchorSeq && record.Hash != o.anchorHash {
+			return count, &VerificationError{Seq: record.Seq,
+				Reason: "hash does not match the anchor: the log was rewritten"}
+		}
+
+		prevSeq, prevHash = record.Seq, record.Hash
+		count++
+	}
+
+	if o.anchored && prevSeq < o.anchorSeq {
+		return count, &VerificationError{Seq: prevSeq + 1,
+			Reason: fmt.Sprintf("the log ends before anchored record %d: the last records were removed", o.anchorSeq)}
+	}
+
+	return count, nil
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("audit log verification failed at record %d: %s", e.Seq, e.Reason)
+}
+
+// IsVerificationError возвращает true, если err содержит *VerificationError
+func IsVerificationError(err error) bool {
+	var verificationErr *VerificationError
+	return errors.As(err, &verificationErr)
+}
+
+func (l *Log) error(err error) {
+	if l.onError != nil {
+		l.onError(err)
+	}
+}
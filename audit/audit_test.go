@@ -0,0 +1,339 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+	"github.com/oplati-by/go-acquiring/audit"
+)
+
+// newFileLog возвращает журнал в новом файле с тремя записями и путь к файлу
+func newFileLog(t *testing.T, opts ...audit.Opt) (*audit.Log, *audit.File, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	backend, err := audit.NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = backend.Close() })
+
+	log, err := audit.New(context.Background(), backend, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		_, err = log.Append(context.Background(), audit.Record{Kind: audit.KindNotification, RequestBody: []byte{byte('a' + i)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return log, backend, path
+}
+
+// readLines возвращает строки файла журнала
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitAfter(string(data), "\n")
+}
+
+// writeLines записывает строки в файл журнала
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// verifyFile проверяет файл журнала, открытый только для чтения
+func verifyFile(t *testing.T, path string, opts ...audit.VerifyOpt) (int, error) {
+	t.Helper()
+
+	backend, err := audit.NewFile(path, audit.WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	return audit.Verify(context.Background(), backend, opts...)
+}
+
+func TestVerify(t *testing.T) {
+	_, _, path := newFileLog(t)
+
+	count, err := verifyFile(t, path)
+	if err != nil || count != 3 {
+		t.Fatalf("Verify() = %d, %v, want 3, nil", count, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(lines []string) []string
+		wantSeq uint64
+	}{
+		{
+			name: "changed record",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"requestBody":"Yg=="`, `"requestBody":"eg=="`, 1)
+				return lines
+			},
+			wantSeq: 2,
+		},
+		{
+			name:    "removed record",
+			tamper:  func(lines []string) []string { return append(lines[:1:1], lines[2:]...) },
+			wantSeq: 3,
+		},
+		{
+			name: "reordered records",
+			tamper: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantSeq: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, path := newFileLog(t)
+			writeLines(t, path, tt.tamper(readLines(t, path)))
+
+			_, err := verifyFile(t, path)
+			var verificationErr *audit.VerificationError
+			if !errors.As(err, &verificationErr) || verificationErr.Seq != tt.wantSeq {
+				t.Fatalf("Verify() = %v, want *VerificationError at record %d", err, tt.wantSeq)
+			}
+		})
+	}
+}
+
+func TestVerifyKey(t *testing.T) {
+	key := []byte("secret")
+	_, _, path := newFileLog(t, audit.WithKey(key))
+
+	count, err := verifyFile(t, path, audit.WithVerifyKey(key))
+	if err != nil || count != 3 {
+		t.Fatalf("Verify(WithVerifyKey) = %d, %v, want 3, nil", count, err)
+	}
+
+	if _, err = verifyFile(t, path); !audit.IsVerificationError(err) {
+		t.Fatalf("Verify() without key = %v, want *VerificationError", err)
+	}
+	if _, err = verifyFile(t, path, audit.WithVerifyKey([]byte("other"))); !audit.IsVerificationError(err) {
+		t.Fatalf("Verify() with other key = %v, want *VerificationError", err)
+	}
+
+	// Измененная запись с хешем, пересчитанным без ключа, обнаруживается
+	lines := readLines(t, path)
+	var record audit.Record
+	if err = json.Unmarshal([]byte(lines[2]), &record); err != nil {
+		t.Fatal(err)
+	}
+	record.RequestBody = []byte("z")
+	record.Hash = ""
+	if record.Hash, err = record.ComputeHash(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(record)
+	lines[2] = string(data) + "\n"
+	writeLines(t, path, lines)
+
+	_, err = verifyFile(t, path, audit.WithVerifyKey(key))
+	var verificationErr *audit.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Seq != 3 {
+		t.Fatalf("Verify() = %v, want *VerificationError at record 3", err)
+	}
+}
+
+func TestVerifyAnchor(t *testing.T) {
+	log, _, path := newFileLog(t)
+	seq, hash := log.Head()
+	if seq != 3 || hash == "" {
+		t.Fatalf("Head() = %d, %q", seq, hash)
+	}
+
+	if _, err := verifyFile(t, path, audit.WithAnchor(seq, hash)); err != nil {
+		t.Fatalf("Verify(WithAnchor) = %v", err)
+	}
+	if _, err := verifyFile(t, path, audit.WithAnchor(seq, strings.Repeat("0", len(hash)))); !audit.IsVerificationError(err) {
+		t.Fatalf("Verify() with wrong anchor hash = %v, want *VerificationError", err)
+	}
+
+	// Удаление последней записи не нарушает цепочку и обнаруживается только по якорю
+	writeLines(t, path, readLines(t, path)[:2])
+	if count, err := verifyFile(t, path); err != nil || count != 2 {
+		t.Fatalf("Verify() = %d, %v, want 2, nil", count, err)
+	}
+
+	_, err := verifyFile(t, path, audit.WithAnchor(seq, hash))
+	var verificationErr *audit.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Seq != 3 {
+		t.Fatalf("Verify(WithAnchor) = %v, want *VerificationError at record 3", err)
+	}
+}
+
+func TestNewFileTornTail(t *testing.T) {
+	_, backend, path := newFileLog(t)
+	if err := backend.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail := []byte(`{"seq":4,"kind":"notif`)
+	writeLines(t, path, []string{string(original), string(tail)})
+
+	_, err = audit.NewFile(path)
+	var tornErr *audit.TornTailError
+	if !errors.As(err, &tornErr) || tornErr.Offset != int64(len(original)) || !bytes.Equal(tornErr.Data, tail) {
+		t.Fatalf("NewFile() = %v, want *TornTailError at %d", err, len(original))
+	}
+	if data, _ := os.ReadFile(path); len(data) != len(original)+len(tail) {
+		t.Fatalf("file was changed without WithTornTailHandler: %d bytes", len(data))
+	}
+
+	handlerErr := errors.New("saving failed")
+	_, err = audit.NewFile(path, audit.WithTornTailHandler(func(audit.TornTailError) error { return handlerErr }))
+	if !errors.Is(err, handlerErr) {
+		t.Fatalf("NewFile() = %v, want handler error", err)
+	}
+
+	var removed audit.TornTailError
+	backend, err = audit.NewFile(path, audit.WithTornTailHandler(func(torn audit.TornTailError) error {
+		removed = torn
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	if !bytes.Equal(removed.Data, tail) {
+		t.Fatalf("handler got %q, want %q", removed.Data, tail)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, original) {
+		t.Fatalf("file after recovery has %d bytes, want %d", len(data), len(original))
+	}
+
+	log, err := audit.New(context.Background(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = log.Append(context.Background(), audit.Record{Kind: audit.KindNotification}); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := verifyFile(t, path); err != nil || count != 4 {
+		t.Fatalf("Verify() = %d, %v, want 4, nil", count, err)
+	}
+}
+
+func TestNewFileNeverTruncatesToZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeLines(t, path, []string{"not a log"})
+
+	called := false
+	_, err := audit.NewFile(path, audit.WithTornTailHandler(func(audit.TornTailError) error {
+		called = true
+		return nil
+	}))
+	var tornErr *audit.TornTailError
+	if !errors.As(err, &tornErr) || tornErr.Offset != 0 || called {
+		t.Fatalf("NewFile() = %v, handler called %v, want *TornTailError at 0", err, called)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "not a log" {
+		t.Fatalf("file was changed: %q", data)
+	}
+}
+
+func TestMiddlewareLargeResponse(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 3<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	log, backend, path := newFileLog(t)
+	doer := log.Middleware()(http.DefaultClient)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := doer.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Читается только часть тела: запись должна содержать размер и хеш всего тела
+	got, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil || !bytes.Equal(got, body[:2<<20]) {
+		t.Fatalf("reading body: %d bytes, %v", len(got), err)
+	}
+	if err = resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	last, ok, err := backend.Last(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("Last() = %v, %v", ok, err)
+	}
+	sum := sha256.Sum256(body)
+	if last.ResponseBody != nil || last.ResponseBodySize != int64(len(body)) ||
+		last.ResponseBodySHA256 != hex.EncodeToString(sum[:]) || last.Error != "" {
+		t.Fatalf("record: size %d, sha256 %s, body %d bytes, error %q", last.ResponseBodySize,
+			last.ResponseBodySHA256, len(last.ResponseBody), last.Error)
+	}
+
+	if count, err := verifyFile(t, path); err != nil || count != 4 {
+		t.Fatalf("Verify() = %d, %v, want 4, nil", count, err)
+	}
+}
+
+func TestMiddlewareSmallResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":1}`))
+	}))
+	defer server.Close()
+
+	log, backend, _ := newFileLog(t)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Password", "1111")
+	resp, err := log.Middleware()(http.DefaultClient).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	last, _, err := backend.Last(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"status":1}` || string(last.ResponseBody) != `{"status":1}` || last.ResponseBodySize != 0 {
+		t.Fatalf("response %q, record body %q, size %d", got, last.ResponseBody, last.ResponseBodySize)
+	}
+	if password := last.RequestHeader.Get("Password"); password != oacquiring.RedactedValue {
+		t.Fatalf("Password header is recorded as %q", password)
+	}
+}
@@ -0,0 +1,316 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"sync"
+)
+
+// maxLineSize - максимальный размер записи в файле журнала. Тела запроса и ответа (тело ответа не более maxBodySize)
+// хранятся в base64 и занимают на треть больше места, поэтому лимит взят с запасом.
+const maxLineSize = 16 * maxBodySize
+
+type (
+	// File - Backend, хранящий записи в файле по одной записи json на строку. Для инициализации используйте NewFile.
+	File struct {
+		mu       sync.Mutex
+		path     string
+		file     *os.File
+		size     int64 // Размер файла после последней успешно добавленной записи
+		sync     bool
+		readOnly bool
+		onTorn   func(TornTailError) error
+	}
+
+	// FileOpt - дополнительные параметры File
+	FileOpt func(*File)
+
+	// TornTailError - последняя строка файла журнала не завершена: запись была прервана сбоем или файл изменен.
+	// Возвращается NewFile, если незавершенную строку нельзя удалить (см. WithTornTailHandler).
+	TornTailError struct {
+		Path   string // Путь к файлу журнала
+		Offset int64  // Смещение начала незавершенной строки в файле
+		Data   []byte // Содержимое незавершенной строки
+	}
+)
+
+// WithoutSync - не вызывать fsync после каждой записи. Увеличивает скорость, но последние записи могут быть потеряны
+// при сбое системы. Чтобы ограничить потери, периодически вызывайте File.Sync.
+func WithoutSync() FileOpt {
+	return func(f *File) {
+		f.sync = false
+	}
+}
+
+// WithReadOnly - открыть существующий файл только для чтения, например для Verify. Файл не изменяется, Append
+// возвращает ошибку, а незавершенная последняя строка возвращается Records как *VerificationError.
+func WithReadOnly() FileOpt {
+	return func(f *File) {
+		f.readOnly = true
+	}
+}
+
+// WithTornTailHandler - функция, получающая незавершенную последнюю строку файла перед ее удалением, например чтобы
+// сохранить ее отдельно и сообщить о сбое. Если функция вернула ошибку, файл не изменяется, а NewFile возвращает эту
+// ошибку. Без WithTornTailHandler NewFile не изменяет файл с незавершенной строкой и возвращает *TornTailError.
+func WithTornTailHandler(onTorn func(TornTailError) error) FileOpt {
+	return func(f *File) {
+		f.onTorn = onTorn
+	}
+}
+
+// NewFile открывает файл журнала path для добавления записей. Если файл не существует, он создается.
+//
+// Если последняя строка файла не завершена (запись была прервана сбоем и не была подтверждена Append), она
+// передается в WithTornTailHandler и удаляется. Без WithTornTailHandler, а также если в файле нет ни одной
+// завершенной строки, файл не изменяется и возвращается *TornTailError.
+func NewFile(path string, opts ...FileOpt) (*File, error) {
+	f := &File{path: path, sync: true}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if f.readOnly {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("audit file opening failed: %w", err)
+		}
+		f.file = file
+		return f, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit file opening failed: %w", err)
+	}
+
+	f.size, err = f.recoverTornTail(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	f.file = file
+
+	return f, nil
+}
+
+// recoverTornTail удаляет из file незавершенную последнюю строку (см. NewFile) и возвращает новый размер файла
+func (f *File) recoverTornTail(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("audit file recovery failed: %w", err)
+	}
+
+	size := info.Size()
+	end, err := lastLineEnd(file, size)
+	if err != nil {
+		return 0, fmt.Errorf("audit file recovery failed: %w", err)
+	}
+	if end == size {
+		return size, nil
+	}
+
+	torn := TornTailError{Path: f.path, Offset: end, Data: make([]byte, size-end)}
+	if _, err = file.ReadAt(torn.Data, end); err != nil {
+		return 0, fmt.Errorf("audit file recovery failed: %w", err)
+	}
+	if end == 0 || f.onTorn == nil {
+		return 0, &torn
+	}
+	if err = f.onTorn(torn); err != nil {
+		return 0, err
+	}
+
+	if err = file.Truncate(end); err != nil {
+		return 0, fmt.Errorf("audit file recovery failed: %w", err)
+	}
+	return end, nil
+}
+
+// lastLineEnd возвращает смещение конца последней завершенной строки file размером size (0, если таких строк нет)
+func lastLineEnd(file *os.File, size int64) (int64, error) {
+	buf := make([]byte, 64<<10)
+	for end := size; end > 0; {
+		start := max(0, end-int64(len(buf)))
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+
+	return 0, nil
+}
+
+func (e *TornTailError) Error() string {
+	return fmt.Sprintf("audit file %s has an incomplete last line at offset %d (%d bytes)", e.Path, e.Offset, len(e.Data))
+}
+
+// Append реализует Backend. Если запись не удалась, файл возвращается к размеру до нее, чтобы следующая запись не
+// была дописана к незавершенной строке.
+func (f *File) Append(_ context.Context, record Record) error {
+	if f.readOnly {
+		return errors.New("audit file is opened read-only")
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("audit record encoding failed: %w", err)
+	}
+	if len(data) >= maxLineSize {
+		return fmt.Errorf("audit record is too large: %d bytes", len(data))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.file.Write(append(data, '\n'))
+	if err == nil && f.sync {
+		err = f.file.Sync()
+	}
+	if err != nil {
+		if n > 0 {
+			_ = f.file.Truncate(f.size)
+		}
+		return err
+	}
+
+	f.size += int64(n)
+	return nil
+}
+
+// Sync сохраняет добавленные записи на диск (fsync). Нужен только с WithoutSync: например, вызов Sync раз в секунду
+// ограничивает потери при сбое записями за последнюю секунду, не замедляя каждый запрос.
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Last реализует Backend
+func (f *File) Last(ctx context.Context) (Record, bool, error) {
+	var (
+		last Record
+		ok   bool
+	)
+
+	for record, err := range f.Records(ctx) {
+		if err != nil {
+			return Record{}, false, err
+		}
+		last, ok = record, true
+	}
+
+	return last, ok, nil
+}
+
+// Records реализует Backend. Читаются только записи, добавленные к моменту вызова.
+func (f *File) Records(ctx context.Context) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		size, err := f.committedSize()
+		if err != nil {
+			yield(Record{}, fmt.Errorf("audit file reading failed: %w", err))
+			return
+		}
+
+		file, err := os.Open(f.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			yield(Record{}, fmt.Errorf("audit file opening failed: %w", err))
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		scanner := bufio.NewScanner(io.LimitReader(file, size))
+		scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+		scanner.Split(scanCompleteLines)
+
+		var (
+			line    int
+			lastSeq uint64
+		)
+		for scanner.Scan() {
+			line++
+			if err = ctx.Err(); err != nil {
+				yield(Record{}, err)
+				return
+			}
+
+			var record Record
+			err = json.Unmarshal(scanner.Bytes(), &record)
+			if err != nil {
+				yield(Record{}, fmt.Errorf("audit file line %d decoding failed: %w", line, err))
+				return
+			}
+			lastSeq = record.Seq
+
+			if !yield(record, nil) {
+				return
+			}
+		}
+
+		err = scanner.Err()
+		if errors.Is(err, errTornTail) {
+			yield(Record{}, &VerificationError{Seq: lastSeq + 1,
+				Reason: "the last line is incomplete: appending was interrupted or the file was truncated"})
+			return
+		}
+		if err != nil {
+			yield(Record{}, fmt.Errorf("audit file reading failed: %w", err))
+		}
+	}
+}
+
+// committedSize возвращает размер файла, до которого записи завершены
+func (f *File) committedSize() (int64, error) {
+	if !f.readOnly {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.size, nil
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+var errTornTail = errors.New("incomplete last line")
+
+// scanCompleteLines - bufio.SplitFunc, возвращающий только строки, завершенные '\n'. Незавершенная последняя строка
+// возвращается как errTornTail.
+func scanCompleteLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return 0, nil, errTornTail
+	}
+	return 0, nil, nil
+}
+
+// Close закрывает файл журнала
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
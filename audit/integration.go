@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// maxBodySize - максимальный размер тела ответа, записываемого в журнал целиком. Для тел большего размера
+// записываются только размер и sha256.
+const maxBodySize = 1 << 20
+
+type (
+	notificationHandler struct {
+		log  *Log
+		next oacquiring.PaymentNotificationHandler
+	}
+
+	// hashingBody - тело ответа, превышающего maxBodySize. Передает тело вызывающему без изменений, вычисляя его
+	// размер и sha256, и добавляет запись в журнал при закрытии.
+	hashingBody struct {
+		io.Reader
+		body   io.ReadCloser
+		hash   hash.Hash
+		size   int64
+		once   sync.Once
+		finish func(size int64, sum string, err error)
+	}
+)
+
+// ClientOpt возвращает oacquiring.ClientOpt, подключающий Middleware
+func (l *Log) ClientOpt() oacquiring.ClientOpt {
+	return oacquiring.WithMiddleware(l.Middleware())
+}
+
+// Middleware возвращает oacquiring.Middleware, записывающий каждый запрос к API и ответ на него (или ошибку
+// выполнения запроса). Если запись не удалось сохранить, вызывается функция WithErrorHandler, а результат запроса
+// возвращается без изменений. Подключайте Middleware последним, чтобы записывались заголовки, добавленные другими
+// Middleware.
+//
+// Тело ответа до 1 МБ записывается целиком. Для ответа с телом большего размера тело передается вызывающему без
+// буферизации, а в журнал при закрытии тела записываются Record.ResponseBodySize и Record.ResponseBodySHA256.
+//
+// Записи добавляются по одной, и каждый запрос ждет сохранения своей записи, в том числе fsync File (см.
+// WithoutSync), поэтому при большом количестве параллельных запросов журнал ограничивает их скорость.
+func (l *Log) Middleware() oacquiring.Middleware {
+	return func(next oacquiring.Doer) oacquiring.Doer {
+		return oacquiring.DoerFunc(func(r *http.Request) (*http.Response, error) {
+			record := Record{
+				Kind:          KindRequest,
+				Method:        r.Method,
+				URL:           r.URL.String(),
+				RequestHeader: oacquiring.RedactHeaders(r.Header, l.redact...),
+			}
+			if op, ok := oacquiring.OperationFromContext(r.Context()); ok {
+				record.Operation = string(op)
+			}
+
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("request body copying failed: %w", err)
+				}
+				record.RequestBody, err = io.ReadAll(body)
+				_ = body.Close()
+				if err != nil {
+					return nil, fmt.Errorf("request body copying failed: %w", err)
+				}
+			}
+
+			resp, err := next.Do(r)
+			if err != nil {
+				record.Error = err.Error()
+				l.appendRecord(r.Context(), record)
+				return nil, err
+			}
+
+			record.StatusCode = resp.StatusCode
+			record.ResponseHeader = oacquiring.RedactHeaders(resp.Header, l.redact...)
+
+			respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+			if err == nil && len(respBody) > maxBodySize {
+				resp.Body = newHashingBody(respBody, resp.Body, func(size int64, sum string, err error) {
+					record.ResponseBodySize, record.ResponseBodySHA256 = size, sum
+					if err != nil {
+						record.Error = fmt.Sprintf("response body reading failed: %s", err)
+					}
+					l.appendRecord(r.Context(), record)
+				})
+				return resp, nil
+			}
+			_ = resp.Body.Close()
+			if err != nil {
+				err = fmt.Errorf("response body reading failed: %w", err)
+				record.Error = err.Error()
+				l.appendRecord(r.Context(), record)
+				return nil, err
+			}
+
+			record.ResponseBody = respBody
+			l.appendRecord(r.Context(), record)
+
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+			return resp, nil
+		})
+	}
+}
+
+// newHashingBody возвращает hashingBody для тела body, из которого уже прочитано prefix. finish вызывается один раз при
+// закрытии тела.
+func newHashingBody(prefix []byte, body io.ReadCloser, finish func(size int64, sum string, err error)) *hashingBody {
+	b := &hashingBody{body: body, hash: sha256.New(), finish: finish}
+	b.Reader = io.TeeReader(io.MultiReader(bytes.NewReader(prefix), body), b)
+	return b
+}
+
+// Write учитывает прочитанные вызывающим данные
+func (b *hashingBody) Write(p []byte) (int, error) {
+	b.size += int64(len(p))
+	return b.hash.Write(p)
+}
+
+// Close дочитывает тело, чтобы размер и хеш относились к телу целиком, закрывает его и добавляет запись в журнал
+func (b *hashingBody) Close() error {
+	b.once.Do(func() {
+		_, err := io.Copy(io.Discard, b.Reader)
+		b.finish(b.size, hex.EncodeToString(b.hash.Sum(nil)), err)
+	})
+	return b.body.Close()
+}
+
+// NotificationHandler возвращает oacquiring.PaymentNotificationHandler, который записывает уведомление с проверенной
+// подписью (исходное тело, заголовки и Server-Sign) и затем вызывает next. Если запись не удалось сохранить, next не
+// вызывается, а серверу Оплати возвращается ошибка, чтобы уведомление было отправлено повторно.
+func (l *Log) NotificationHandler(next oacquiring.PaymentNotificationHandler) oacquiring.PaymentNotificationHandler {
+	return &notificationHandler{log: l, next: next}
+}
+
+// HandlePayment реализует oacquiring.PaymentNotificationHandler. oacquiring.HTTPNotificationHandler вызывает
+// ReceiveNotification; при прямом вызове HandlePayment исходное тело недоступно, и в журнал записывается тело,
+// восстановленное из payment, без подписи.
+func (h *notificationHandler) HandlePayment(payment oacquiring.PaymentInfo) error {
+	body, err := oacquiring.MarshalNotification(payment)
+	if err != nil {
+		return err
+	}
+	return h.ReceiveNotification(context.Background(), oacquiring.Notification{Payment: payment, Body: body})
+}
+
+// ReceiveNotification реализует oacquiring.NotificationReceiver
+func (h *notificationHandler) ReceiveNotification(ctx context.Context, notification oacquiring.Notification) error {
+	_, err := h.log.Append(ctx, Record{
+		Kind:          KindNotification,
+		RequestHeader: oacquiring.RedactHeaders(notification.Header, h.log.redact...),
+		RequestBody:   notification.Body,
+		Signature:     notification.Header.Get(oacquiring.ServerSignHeader),
+	})
+	if err != nil {
+		return err
+	}
+
+	if h.next == nil {
+		return nil
+	}
+	return oacquiring.DeliverNotification(ctx, h.next, notification)
+}
+
+func (l *Log) appendRecord(ctx context.Context, record Record) {
+	_, err := l.Append(context.WithoutCancel(ctx), record)
+	if err != nil {
+		l.error(err)
+	}
+}
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+const (
+	recordsTable    = "oplati_audit_log"
+	migrationsTable = "oplati_audit_migrations"
+)
+
+var (
+	// Postgres - диалект PostgreSQL
+	Postgres = Dialect{
+		name:        "postgres",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		migrations: [][]string{
+			{
+				`CREATE TABLE IF NOT EXISTS oplati_audit_log (
+					seq BIGINT PRIMARY KEY,
+					recorded_at TIMESTAMPTZ NOT NULL,
+					kind TEXT NOT NULL,
+					record TEXT NOT NULL,
+					hash TEXT NOT NULL
+				)`,
+			},
+		},
+	}
+
+	// SQLite - диалект SQLite
+	SQLite = Dialect{
+		name:        "sqlite",
+		placeholder: func(int) string { return "?" },
+		migrations: [][]string{
+			{
+				`CREATE TABLE IF NOT EXISTS oplati_audit_log (
+					seq INTEGER PRIMARY KEY,
+					recorded_at DATETIME NOT NULL,
+					kind TEXT NOT NULL,
+					record TEXT NOT NULL,
+					hash TEXT NOT NULL
+				)`,
+			},
+		},
+	}
+)
+
+type (
+	// Dialect - диалект SQL. Варианты: Postgres, SQLite
+	Dialect struct {
+		name        string
+		placeholder func(n int) string
+		migrations  [][]string
+	}
+
+	// SQL - Backend, хранящий записи в таблице oplati_audit_log. Запись хранится в json без изменений, поэтому хеш
+	// проверяется по сохраненному содержимому. Перед использованием примените миграции с помощью Migrate. Для
+	// инициализации используйте NewSQL.
+	SQL struct {
+		db      *sql.DB
+		dialect Dialect
+	}
+)
+
+func (d Dialect) String() string {
+	return d.name
+}
+
+// query заменяет плейсхолдеры "?" в query на плейсхолдеры диалекта
+func (d Dialect) query(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString(d.placeholder(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Migrate создает или обновляет таблицу oplati_audit_log. Примененные миграции записываются в таблицу
+// oplati_audit_migrations, поэтому Migrate можно вызывать при каждом запуске приложения.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("migrations table creation failed: %w", err)
+	}
+
+	var version int
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM `+migrationsTable).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("schema version reading failed: %w", err)
+	}
+
+	for i := version; i < len(dialect.migrations); i++ {
+		err = applyMigration(ctx, db, dialect, i+1)
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, dialect Dialect, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, statement := range dialect.migrations[version-1] {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, dialect.query(`INSERT INTO `+migrationsTable+` (version) VALUES (?)`), version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NewSQL возвращает новый SQL
+func NewSQL(db *sql.DB, dialect Dialect) *SQL {
+	return &SQL{db: db, dialect: dialect}
+}
+
+// Append реализует Backend. Первичный ключ seq не позволяет двум записям получить один номер.
+func (s *SQL) Append(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("audit record encoding failed: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.query(`INSERT INTO `+recordsTable+` (seq, recorded_at, kind, record, hash)
+		VALUES (?, ?, ?, ?, ?)`),
+		int64(record.Seq), record.Time, record.Kind, string(data), record.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("audit record insertion failed: %w", err)
+	}
+
+	return nil
+}
+
+// Last реализует Backend
+func (s *SQL) Last(ctx context.Context) (Record, bool, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT record FROM `+recordsTable+` ORDER BY seq DESC LIMIT 1`).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, fmt.Errorf("audit record query failed: %w", err)
+	}
+
+	record, err := decodeRecord(data)
+	if err != nil {
+		return Record{}, false, err
+	}
+	return record, true, nil
+}
+
+// Records реализует Backend
+func (s *SQL) Records(ctx context.Context) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		rows, err := s.db.QueryContext(ctx, `SELECT seq, record FROM `+recordsTable+` ORDER BY seq`)
+		if err != nil {
+			yield(Record{}, fmt.Errorf("audit records query failed: %w", err))
+			return
+		}
+		defer func() { _ = rows.Close() }()
+
+		for rows.Next() {
+			var (
+				seq  int64
+				data string
+			)
+			err = rows.Scan(&seq, &data)
+			if err != nil {
+				yield(Record{}, fmt.Errorf("audit record scanning failed: %w", err))
+				return
+			}
+
+			record, err := decodeRecord(data)
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			if uint64(seq) != record.Seq {
+				yield(Record{}, &VerificationError{Seq: uint64(seq), Reason: "record number does not match its row"})
+				return
+			}
+
+			if !yield(record, nil) {
+				return
+			}
+		}
+
+		if err = rows.Err(); err != nil {
+			yield(Record{}, fmt.Errorf("audit records query failed: %w", err))
+		}
+	}
+}
+
+func decodeRecord(data string) (Record, error) {
+	var record Record
+	err := json.Unmarshal([]byte(data), &record)
+	if err != nil {
+		return Record{}, fmt.Errorf("audit record decoding failed: %w", err)
+	}
+	return record, nil
+}
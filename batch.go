@@ -0,0 +1,163 @@
+package oacquiring
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultBatchConcurrency - количество одновременных запросов GetPaymentInfos по умолчанию
+const defaultBatchConcurrency = 4
+
+type (
+	// BatchOptions - параметры GetPaymentInfos и PaymentInfos
+	BatchOptions struct {
+		// Concurrency - максимальное количество одновременных запросов. Если < 1, используется 4
+		Concurrency int
+		// Rate - максимальное среднее количество запросов в секунду для этого вызова. 0 - без ограничения. Ограничения
+		// WithRateLimit соблюдаются независимо от Rate
+		Rate float64
+		// Burst - максимальное количество запросов, которые можно выполнить сразу при Rate > 0. Если < 1, используется 1
+		Burst int
+		// OnResult вызывается GetPaymentInfos для каждого результата в порядке получения. Вызовы не выполняются
+		// одновременно
+		OnResult func(PaymentInfoResult)
+	}
+
+	// PaymentInfoResult - результат получения информации об одном платеже
+	PaymentInfoResult struct {
+		PaymentId int64       // Идентификатор платежа
+		Info      PaymentInfo // Информация о платеже, если Err == nil
+		Err       error       // Ошибка GetPaymentInfo
+	}
+)
+
+// GetPaymentInfos получает информацию о платежах ids с помощью GetPaymentInfo, выполняя не более
+// BatchOptions.Concurrency запросов одновременно. Возвращает результаты в порядке ids. Ошибки отдельных платежей
+// возвращаются в PaymentInfoResult.Err.
+//
+// Если ctx отменен, выполняемые запросы прерываются, необработанные платежи получают ошибку ctx, и GetPaymentInfos
+// возвращает ctx.Err(). Если к моменту отмены получены результаты по всем платежам, ошибка не возвращается.
+func (a *Client) GetPaymentInfos(ctx context.Context, ids []int64, opts BatchOptions) ([]PaymentInfoResult, error) {
+	results := make([]PaymentInfoResult, len(ids))
+	done := make([]bool, len(ids))
+
+	a.batchPaymentInfos(ctx, ids, opts, func(i int, result PaymentInfoResult) bool {
+		results[i], done[i] = result, true
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+		return true
+	})
+
+	var err error
+	for i := range results {
+		if !done[i] {
+			// Результаты отсутствуют, только если ctx отменен
+			err = ctx.Err()
+			results[i] = PaymentInfoResult{PaymentId: ids[i], Err: err}
+		}
+	}
+
+	return results, err
+}
+
+// PaymentInfos возвращает итератор, получающий информацию о платежах ids так же, как GetPaymentInfos, и выдающий
+// результаты в порядке получения. При ошибке PaymentInfo содержит только Id. Выход из цикла прерывает оставшиеся
+// запросы; после отмены ctx итератор завершается, не выдавая необработанные платежи.
+//
+//	for info, err := range oplatiClient.PaymentInfos(ctx, ids, oacquiring.BatchOptions{Concurrency: 8}) {
+//	    if err != nil {
+//	        log.Printf("payment %d: %s", info.Id, err)
+//	        continue
+//	    }
+//	    // ...
+//	}
+func (a *Client) PaymentInfos(ctx context.Context, ids []int64, opts BatchOptions) iter.Seq2[PaymentInfo, error] {
+	return func(yield func(PaymentInfo, error) bool) {
+		a.batchPaymentInfos(ctx, ids, opts, func(_ int, result PaymentInfoResult) bool {
+			if result.Err != nil {
+				return yield(PaymentInfo{Id: result.PaymentId}, result.Err)
+			}
+			return yield(result.Info, nil)
+		})
+	}
+}
+
+// batchPaymentInfos выполняет GetPaymentInfo для ids в пуле горутин и вызывает handle в вызывающей горутине в порядке
+// получения результатов. Если handle вернул false или ctx отменен, оставшиеся запросы прерываются. Возвращается после
+// завершения всех горутин.
+func (a *Client) batchPaymentInfos(ctx context.Context, ids []int64, opts BatchOptions, handle func(int, PaymentInfoResult) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = defaultBatchConcurrency
+	}
+	workers = min(workers, len(ids))
+
+	var rate *limiter
+	if opts.Rate > 0 {
+		rate = newLimiter(RateLimit{Rate: opts.Rate, Burst: opts.Burst})
+	}
+
+	type indexedResult struct {
+		index  int
+		result PaymentInfoResult
+	}
+
+	var (
+		next    atomic.Int64
+		wg      sync.WaitGroup
+		results = make(chan indexedResult)
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(ids) || ctx.Err() != nil {
+					return
+				}
+
+				if rate != nil {
+					if rate.wait(ctx) != nil {
+						return
+					}
+					rate.release()
+				}
+
+				info, err := a.GetPaymentInfo(ctx, ids[i])
+				result := indexedResult{index: i, result: PaymentInfoResult{PaymentId: ids[i], Info: info, Err: err}}
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Результаты вычитываются до закрытия канала, чтобы все горутины завершились до возврата
+	stopped := false
+	for r := range results {
+		if stopped {
+			continue
+		}
+		if !handle(r.index, r.result) {
+			stopped = true
+			cancel()
+		}
+	}
+}
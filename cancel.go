@@ -0,0 +1,50 @@
+package oacquiring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ErrPaymentAlreadyFinished - платеж уже в окончательном статусе и не может быть отменен. Возвращается CancelPayment,
+// если статус в ответе сервера окончательный.
+var ErrPaymentAlreadyFinished = errors.New("payment is already finished")
+
+// CancelPayment - отмена платежа, который еще не подтвержден покупателем (статус PaymentStatusInProgress), со стороны
+// кассы. Подходит для платежей, созданных методами CreatePayment и CreatePOSPayment, например для брошенных корзин.
+// Возвращает PaymentInfo со статусом PaymentStatusTechCancel. Используется запрос PUT /pos/payments/{paymentId}/cancel.
+//
+// Экспериментальный метод: запрос отмены отсутствует в опубликованном описании API Оплати, его адрес и ответ сервера для
+// уже завершенного платежа не подтверждены. Метод может быть изменен или удален.
+//
+// Если сервер вернул PaymentInfo с окончательным статусом (например, оплачен), он возвращается вместе с ошибкой, для
+// которой errors.Is(err, ErrPaymentAlreadyFinished) == true. Оплаченный платеж можно вернуть с помощью ReversePayment.
+//
+// В случае, если сервер вернул ответ отличный от 200 OK, возвращаемый error можно попробовать привести к *ServerError
+// для получения дополнительных данных об ошибке.
+func (a *Client) CancelPayment(ctx context.Context, paymentId int64) (PaymentInfo, error) {
+	creds, err := a.getCredentials(ctx)
+	if err != nil {
+		return PaymentInfo{}, err
+	}
+
+	var rawPaymentInfo paymentInfoResponse
+	err = a.do(ctx, creds, OperationCancelPayment, http.MethodPut, "/pos/payments/"+strconv.FormatInt(paymentId, 10)+"/cancel", nil, &rawPaymentInfo)
+	if err != nil {
+		return PaymentInfo{}, err
+	}
+
+	paymentInfo, err := makePaymentInfoFromRaw(rawPaymentInfo)
+	if err != nil {
+		return PaymentInfo{}, fmt.Errorf("handling response failed: %w", err)
+	}
+	a.paymentInfoReceived(ctx, OperationCancelPayment, paymentInfo)
+
+	if paymentInfo.Status != PaymentStatusTechCancel && paymentInfo.Status != PaymentStatusInProgress {
+		return paymentInfo, fmt.Errorf("payment %d is already in status %s: %w", paymentId, paymentInfo.Status, ErrPaymentAlreadyFinished)
+	}
+
+	return paymentInfo, nil
+}
@@ -0,0 +1,314 @@
+// Package capture содержит HTTP сервер для разработки, принимающий уведомления Оплати. Сервер проверяет подпись
+// каждого уведомления, передает его настоящему обработчику и сохраняет доставку (заголовки, тело, результат проверки
+// подписи и ответ обработчика) в Store. Сохраненные доставки можно просматривать в браузере или через json API и
+// повторно отправлять обработчику:
+//
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, &Handler{})
+//	// ...
+//	server, err := capture.New(key, &handler, capture.WithStore(capture.NewDirStore("deliveries")))
+//	// ...
+//	http.ListenAndServe("localhost:8080", server)
+//
+// Уведомления принимаются POST запросом по любому пути, кроме UIPrefix. Интерфейс доступен по адресу UIPrefix:
+//   - GET /_capture/ - список доставок
+//   - GET /_capture/{id} - доставка
+//   - GET /_capture/api/deliveries - список доставок в формате json
+//   - GET /_capture/api/deliveries/{id} - доставка в формате json
+//   - GET /_capture/api/deliveries/{id}/raw - HTTP запрос доставки (подходит для oplati verify -request)
+//   - POST /_capture/api/deliveries/{id}/replay - повторная отправка доставки обработчику
+//
+// Сервер не предназначен для работы в production: интерфейс не требует авторизации.
+package capture
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// UIPrefix - путь интерфейса просмотра доставок
+const UIPrefix = "/_capture/"
+
+// maxBodySize - максимальный размер сохраняемого тела уведомления и ответа обработчика
+const maxBodySize = 1 << 20
+
+type (
+	// Delivery - доставка уведомления
+	Delivery struct {
+		Id              int64       `json:"id"`                        // Порядковый номер доставки
+		ReplayOf        int64       `json:"replayOf,omitempty"`        // Номер повторно отправленной доставки
+		ReceivedAt      time.Time   `json:"receivedAt"`                // Время получения
+		Method          string      `json:"method"`                    // HTTP метод
+		Host            string      `json:"host"`                      // Host запроса
+		Path            string      `json:"path"`                      // Путь и параметры запроса
+		RemoteAddr      string      `json:"remoteAddr"`                // Адрес отправителя
+		Header          http.Header `json:"header"`                    // Заголовки запроса
+		Body            string      `json:"body"`                      // Тело запроса
+		Verified        bool        `json:"verified"`                  // Подпись Server-Sign верна
+		VerifyError     string      `json:"verifyError,omitempty"`     // Ошибка проверки подписи
+		PaymentId       int64       `json:"paymentId,omitempty"`       // Идентификатор платежа из тела
+		PaymentStatus   string      `json:"paymentStatus,omitempty"`   // Статус платежа из тела
+		ParseError      string      `json:"parseError,omitempty"`      // Ошибка разбора тела
+		HandlerStatus   int         `json:"handlerStatus"`             // HTTP код ответа обработчика
+		HandlerResponse string      `json:"handlerResponse,omitempty"` // Тело ответа обработчика
+		Duration        Duration    `json:"duration"`                  // Время обработки
+	}
+
+	// Duration - time.Duration, который в json записывается строкой вида "1.5ms"
+	Duration time.Duration
+
+	// Server - сервер приема уведомлений для разработки, реализует http.Handler. Для инициализации используйте New.
+	Server struct {
+		publicKey *rsa.PublicKey
+		handler   http.Handler
+		store     Store
+		ui        http.Handler
+		onError   func(error)
+
+		mu sync.Mutex // упорядочивает доставки в Store
+	}
+
+	// Opt - дополнительные параметры Server
+	Opt func(*Server)
+)
+
+// WithStore - хранилище доставок. По умолчанию NewMemoryStore(1000)
+func WithStore(store Store) Opt {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithErrorHandler - функция, вызываемая, если доставку не удалось сохранить в Store. По умолчанию ошибка выводится
+// через стандартный log
+func WithErrorHandler(onError func(error)) Opt {
+	return func(s *Server) {
+		s.onError = onError
+	}
+}
+
+// New возвращает новый Server.
+//   - publicKey - публичный ключ Оплати для проверки подписи. Если пустой, подпись не проверяется
+//   - handler - настоящий обработчик уведомлений, например oacquiring.HTTPNotificationHandler или ForwardHandler.
+//     Если nil, уведомлениям с верной подписью отвечает "200 OK", с неверной - "401 Unauthorized"
+func New(publicKey string, handler http.Handler, opts ...Opt) (*Server, error) {
+	s := &Server{handler: handler}
+
+	if publicKey != "" {
+		key, err := oacquiring.ParsePublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		s.publicKey = key
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.store == nil {
+		s.store = NewMemoryStore(1000)
+	}
+	if s.onError == nil {
+		s.onError = func(err error) { log.Printf("capture: %s", err) }
+	}
+
+	s.ui = s.newUI()
+
+	return s, nil
+}
+
+// Store возвращает хранилище доставок
+func (s *Server) Store() Store {
+	return s.store
+}
+
+// ServeHTTP реализует http.Handler. Отправителю возвращается ответ обработчика, даже если доставку не удалось
+// сохранить: иначе сервер Оплати повторил бы уже обработанное уведомление. Ошибка сохранения передается в
+// WithErrorHandler. Уведомление с телом больше 1 МБ не передается обработчику и не сохраняется: отправитель получает
+// ответ "413 Request Entity Too Large", а ошибка передается в WithErrorHandler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, UIPrefix) {
+		s.ui.ServeHTTP(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "notifications are accepted with POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBodySize {
+		// Усеченное тело не прошло бы проверку подписи, и доставка выглядела бы как ошибка подписи
+		err = fmt.Errorf("notification body from %s exceeds %d bytes and was rejected", r.RemoteAddr, maxBodySize)
+		s.onError(err)
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	_, resp, err := s.deliver(r, body, 0)
+	if err != nil {
+		s.onError(err)
+	}
+
+	for key, values := range resp.Header() {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.Code)
+	_, _ = w.Write(resp.Body.Bytes())
+}
+
+// Replay повторно отправляет сохраненную доставку обработчику и возвращает новую доставку
+func (s *Server) Replay(id int64) (Delivery, error) {
+	original, err := s.store.Get(id)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	req, err := http.NewRequest(original.Method, original.Path, strings.NewReader(original.Body))
+	if err != nil {
+		return Delivery{}, fmt.Errorf("request initialization failed: %w", err)
+	}
+	req.Header = original.Header.Clone()
+	req.Host = original.Host
+	req.RemoteAddr = "replay"
+
+	delivery, _, err := s.deliver(req, []byte(original.Body), id)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// deliver проверяет уведомление, передает его обработчику и сохраняет доставку. Ответ обработчика возвращается и
+// при ошибке сохранения.
+func (s *Server) deliver(r *http.Request, body []byte, replayOf int64) (Delivery, *httptest.ResponseRecorder, error) {
+	start := time.Now()
+	delivery := Delivery{
+		ReplayOf:   replayOf,
+		ReceivedAt: start,
+		Method:     r.Method,
+		Host:       r.Host,
+		Path:       r.URL.RequestURI(),
+		RemoteAddr: r.RemoteAddr,
+		Header:     r.Header.Clone(),
+		Body:       string(body),
+	}
+
+	var verifyErr error
+	if s.publicKey != nil {
+		verifyErr = oacquiring.VerifyNotification(s.publicKey, body, r.Header.Get(oacquiring.ServerSignHeader))
+		delivery.Verified = verifyErr == nil
+		if verifyErr != nil {
+			delivery.VerifyError = verifyErr.Error()
+		}
+	} else {
+		delivery.VerifyError = "public key is not configured"
+	}
+
+	payment, err := oacquiring.ParseNotification(body)
+	if err != nil {
+		delivery.ParseError = err.Error()
+	} else {
+		delivery.PaymentId = payment.Id
+		delivery.PaymentStatus = payment.Status.String()
+	}
+
+	resp := httptest.NewRecorder()
+	switch {
+	case s.handler != nil:
+		req := r.Clone(r.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		s.handler.ServeHTTP(resp, req)
+	case verifyErr != nil:
+		http.Error(resp, verifyErr.Error(), http.StatusUnauthorized)
+	default:
+		resp.WriteHeader(http.StatusOK)
+	}
+
+	delivery.HandlerStatus = resp.Code
+	delivery.HandlerResponse = string(truncate(resp.Body.Bytes(), maxBodySize))
+	delivery.Duration = Duration(time.Since(start))
+
+	s.mu.Lock()
+	saved, err := s.store.Add(delivery)
+	s.mu.Unlock()
+	if err != nil {
+		return Delivery{}, resp, fmt.Errorf("delivery saving failed: %w", err)
+	}
+
+	return saved, resp, nil
+}
+
+// ForwardHandler возвращает http.Handler, отправляющий уведомление с исходными заголовками на url. Используйте, если
+// настоящий обработчик работает в отдельном процессе.
+func ForwardHandler(url string, client *http.Client) http.Handler {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, url, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		req.Header = r.Header.Clone()
+		req.ContentLength = r.ContentLength
+
+		resp, err := client.Do(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		for key, values := range resp.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, io.LimitReader(resp.Body, maxBodySize))
+	})
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText реализует encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText реализует encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func truncate(data []byte, size int) []byte {
+	if len(data) > size {
+		return data[:size]
+	}
+	return data
+}
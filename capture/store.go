@@ -0,0 +1,238 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound - доставка не найдена
+var ErrNotFound = errors.New("delivery not found")
+
+type (
+	// Store - хранилище доставок
+	Store interface {
+		// Add сохраняет доставку, присваивая ей следующий порядковый номер, и возвращает сохраненную доставку
+		Add(delivery Delivery) (Delivery, error)
+		// Get возвращает доставку по номеру или ErrNotFound
+		Get(id int64) (Delivery, error)
+		// List возвращает не более limit последних доставок, начиная с новых. limit <= 0 - без ограничения
+		List(limit int) ([]Delivery, error)
+	}
+
+	// MemoryStore - Store в памяти, хранящий ограниченное количество последних доставок. Для инициализации используйте
+	// NewMemoryStore.
+	MemoryStore struct {
+		mu         sync.RWMutex
+		capacity   int
+		lastId     int64
+		deliveries []Delivery
+	}
+
+	// DirStore - Store в каталоге. Каждая доставка сохраняется в файлы <id>.json и <id>.http (HTTP запрос, который
+	// можно передать oplati verify -request и oplati replay -request). Для инициализации используйте NewDirStore.
+	DirStore struct {
+		mu     sync.Mutex
+		dir    string
+		lastId int64
+	}
+)
+
+// NewMemoryStore возвращает новый MemoryStore, хранящий не более capacity доставок
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryStore{capacity: capacity}
+}
+
+// Add реализует Store
+func (m *MemoryStore) Add(delivery Delivery) (Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastId++
+	delivery.Id = m.lastId
+
+	if len(m.deliveries) == m.capacity {
+		m.deliveries = slices.Delete(m.deliveries, 0, 1)
+	}
+	m.deliveries = append(m.deliveries, delivery)
+
+	return delivery, nil
+}
+
+// Get реализует Store
+func (m *MemoryStore) Get(id int64) (Delivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, delivery := range m.deliveries {
+		if delivery.Id == id {
+			return delivery, nil
+		}
+	}
+	return Delivery{}, ErrNotFound
+}
+
+// List реализует Store
+func (m *MemoryStore) List(limit int) ([]Delivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := slices.Clone(m.deliveries)
+	slices.Reverse(deliveries)
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// NewDirStore возвращает новый DirStore. Каталог dir создается, если не существует. Нумерация продолжается после
+// доставок, уже сохраненных в каталоге.
+func NewDirStore(dir string) (*DirStore, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("directory creation failed: %w", err)
+	}
+
+	ids, err := listIds(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DirStore{dir: dir}
+	if len(ids) > 0 {
+		d.lastId = ids[len(ids)-1]
+	}
+	return d, nil
+}
+
+// Add реализует Store
+func (d *DirStore) Add(delivery Delivery) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery.Id = d.lastId + 1
+
+	data, err := json.MarshalIndent(delivery, "", "  ")
+	if err != nil {
+		return Delivery{}, fmt.Errorf("delivery encoding failed: %w", err)
+	}
+
+	var raw bytes.Buffer
+	err = delivery.WriteRaw(&raw)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	err = os.WriteFile(d.path(delivery.Id, ".http"), raw.Bytes(), 0o644)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("delivery writing failed: %w", err)
+	}
+	err = os.WriteFile(d.path(delivery.Id, ".json"), data, 0o644)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("delivery writing failed: %w", err)
+	}
+
+	d.lastId = delivery.Id
+	return delivery, nil
+}
+
+// Get реализует Store
+func (d *DirStore) Get(id int64) (Delivery, error) {
+	data, err := os.ReadFile(d.path(id, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		return Delivery{}, fmt.Errorf("delivery reading failed: %w", err)
+	}
+
+	var delivery Delivery
+	err = json.Unmarshal(data, &delivery)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("delivery decoding failed: %w", err)
+	}
+	return delivery, nil
+}
+
+// List реализует Store
+func (d *DirStore) List(limit int) ([]Delivery, error) {
+	ids, err := listIds(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(ids)
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	deliveries := make([]Delivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := d.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (d *DirStore) path(id int64, ext string) string {
+	return filepath.Join(d.dir, fmt.Sprintf("%06d%s", id, ext))
+}
+
+// listIds возвращает номера доставок, сохраненных в dir, по возрастанию
+func listIds(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("directory reading failed: %w", err)
+	}
+
+	var ids []int64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// WriteRaw записывает доставку как HTTP запрос в формате httputil.DumpRequest
+func (d Delivery) WriteRaw(w io.Writer) error {
+	req, err := http.NewRequest(d.Method, d.Path, strings.NewReader(d.Body))
+	if err != nil {
+		return fmt.Errorf("request initialization failed: %w", err)
+	}
+	req.Header = d.Header.Clone()
+	req.Header.Set("Content-Length", strconv.Itoa(len(d.Body)))
+	req.Host = d.Host
+
+	dump, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		return fmt.Errorf("request dumping failed: %w", err)
+	}
+
+	_, err = w.Write(dump)
+	return err
+}
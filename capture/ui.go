@@ -0,0 +1,152 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// listLimit - количество доставок на странице списка
+const listLimit = 200
+
+var (
+	listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Уведомления Оплати</title>` + style + `</head><body>
+<h1>Уведомления Оплати</h1>
+<table>
+<tr><th>#</th><th>Получено</th><th>Подпись</th><th>Платеж</th><th>Статус</th><th>Ответ обработчика</th><th>Время</th><th></th></tr>
+{{range .}}<tr>
+<td><a href="{{.Id}}">{{.Id}}</a>{{if .ReplayOf}} (повтор #{{.ReplayOf}}){{end}}</td>
+<td>{{.ReceivedAt.Format "2006-01-02 15:04:05"}}</td>
+<td class="{{if .Verified}}ok{{else}}fail{{end}}">{{if .Verified}}верна{{else}}{{.VerifyError}}{{end}}</td>
+<td>{{if .PaymentId}}{{.PaymentId}}{{else}}{{.ParseError}}{{end}}</td>
+<td>{{.PaymentStatus}}</td>
+<td class="{{if lt .HandlerStatus 300}}ok{{else}}fail{{end}}">{{.HandlerStatus}}</td>
+<td>{{.Duration}}</td>
+<td><form method="post" action="api/deliveries/{{.Id}}/replay?redirect=1"><button>Повторить</button></form></td>
+</tr>{{else}}<tr><td colspan="8">Уведомлений пока нет</td></tr>{{end}}
+</table>
+</body></html>`))
+
+	deliveryTemplate = template.Must(template.New("delivery").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Доставка #{{.Id}}</title>` + style + `</head><body>
+<p><a href="./">&larr; Все уведомления</a></p>
+<h1>Доставка #{{.Id}}{{if .ReplayOf}} (повтор <a href="{{.ReplayOf}}">#{{.ReplayOf}}</a>){{end}}</h1>
+<table>
+<tr><th>Получено</th><td>{{.ReceivedAt.Format "2006-01-02 15:04:05.000"}} от {{.RemoteAddr}}</td></tr>
+<tr><th>Запрос</th><td>{{.Method}} {{.Path}}</td></tr>
+<tr><th>Подпись</th><td class="{{if .Verified}}ok{{else}}fail{{end}}">{{if .Verified}}верна{{else}}{{.VerifyError}}{{end}}</td></tr>
+<tr><th>Платеж</th><td>{{if .PaymentId}}{{.PaymentId}} {{.PaymentStatus}}{{else}}{{.ParseError}}{{end}}</td></tr>
+<tr><th>Ответ обработчика</th><td class="{{if lt .HandlerStatus 300}}ok{{else}}fail{{end}}">{{.HandlerStatus}} за {{.Duration}}<pre>{{.HandlerResponse}}</pre></td></tr>
+</table>
+<h2>Заголовки</h2>
+<table>{{range $key, $values := .Header}}{{range $values}}<tr><th>{{$key}}</th><td>{{.}}</td></tr>{{end}}{{end}}</table>
+<h2>Тело</h2>
+<pre>{{.Body}}</pre>
+<p><a href="api/deliveries/{{.Id}}/raw">HTTP запрос</a> &middot; <a href="api/deliveries/{{.Id}}">json</a></p>
+<form method="post" action="api/deliveries/{{.Id}}/replay?redirect=1"><button>Повторить</button></form>
+</body></html>`))
+)
+
+const style = `<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { white-space: pre-wrap; word-break: break-all; margin: 0; }
+.ok { color: #080; }
+.fail { color: #c00; }
+</style>`
+
+// newUI возвращает обработчик интерфейса просмотра доставок
+func (s *Server) newUI() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+UIPrefix+"{$}", func(w http.ResponseWriter, r *http.Request) {
+		deliveries, err := s.store.List(listLimit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeHTML(w, listTemplate, deliveries)
+	})
+
+	mux.HandleFunc("GET "+UIPrefix+"{id}", s.withDelivery(func(w http.ResponseWriter, r *http.Request, delivery Delivery) {
+		writeHTML(w, deliveryTemplate, delivery)
+	}))
+
+	mux.HandleFunc("GET "+UIPrefix+"api/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		deliveries, err := s.store.List(limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if deliveries == nil {
+			deliveries = []Delivery{}
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+	})
+
+	mux.HandleFunc("GET "+UIPrefix+"api/deliveries/{id}", s.withDelivery(func(w http.ResponseWriter, r *http.Request, delivery Delivery) {
+		writeJSON(w, http.StatusOK, delivery)
+	}))
+
+	mux.HandleFunc("GET "+UIPrefix+"api/deliveries/{id}/raw", s.withDelivery(func(w http.ResponseWriter, r *http.Request, delivery Delivery) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = delivery.WriteRaw(w)
+	}))
+
+	mux.HandleFunc("POST "+UIPrefix+"api/deliveries/{id}/replay", s.withDelivery(func(w http.ResponseWriter, r *http.Request, delivery Delivery) {
+		replayed, err := s.Replay(delivery.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Query().Has("redirect") {
+			http.Redirect(w, r, UIPrefix+strconv.FormatInt(replayed.Id, 10), http.StatusSeeOther)
+			return
+		}
+		writeJSON(w, http.StatusCreated, replayed)
+	}))
+
+	return mux
+}
+
+// withDelivery загружает доставку по параметру пути {id}
+func (s *Server) withDelivery(handle func(http.ResponseWriter, *http.Request, Delivery)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		delivery, err := s.store.Get(id)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		handle(w, r, delivery)
+	}
+}
+
+func writeHTML(w http.ResponseWriter, tmpl *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = tmpl.Execute(w, data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(data)
+}
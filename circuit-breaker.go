@@ -0,0 +1,237 @@
+package oacquiring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// CircuitClosed - запросы выполняются, ошибки подсчитываются
+	CircuitClosed CircuitState = iota
+	// CircuitOpen - запросы не выполняются, возвращается ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen - выполняется ограниченное количество пробных запросов
+	CircuitHalfOpen
+)
+
+const (
+	defaultFailureThreshold    = 5
+	defaultOpenTimeout         = 30 * time.Second
+	defaultHalfOpenMaxRequests = 1
+)
+
+// ErrCircuitOpen - запрос не выполнен, так как автоматический выключатель разомкнут. Возвращается сразу, без
+// обращения к серверу Оплати, что позволяет быстро предложить покупателю другой способ оплаты.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type (
+	// CircuitState - состояние автоматического выключателя. Варианты: CircuitClosed, CircuitOpen, CircuitHalfOpen
+	CircuitState int
+
+	// CircuitBreakerSettings - настройки автоматического выключателя. Ошибкой считаются сетевые ошибки и
+	// *ServerError с кодом ответа 429 или 5xx (Retryable() == true). Ошибки бизнес-логики (HTTP 4xx) и отмена
+	// контекста не учитываются. Для подключения используйте WithCircuitBreaker.
+	CircuitBreakerSettings struct {
+		// FailureThreshold - количество ошибок подряд, после которого выключатель размыкается. По умолчанию 5
+		FailureThreshold int
+		// OpenTimeout - время, через которое разомкнутый выключатель переходит в CircuitHalfOpen. По умолчанию 30 секунд
+		OpenTimeout time.Duration
+		// HalfOpenMaxRequests - количество одновременных пробных запросов в CircuitHalfOpen. По умолчанию 1
+		HalfOpenMaxRequests int
+		// OnStateChange вызывается при смене состояния выключателя операции op. Может быть nil
+		OnStateChange func(op Operation, from, to CircuitState)
+	}
+
+	// circuitBreakers - автоматические выключатели Client по операциям
+	circuitBreakers struct {
+		byOperation map[Operation]*circuitBreaker
+		fallback    *circuitBreaker
+	}
+
+	circuitBreaker struct {
+		settings CircuitBreakerSettings
+
+		notifyMu         sync.Mutex
+		mu               sync.Mutex
+		state            CircuitState
+		failures         int
+		openedAt         time.Time
+		halfOpenInFlight int
+		generation       uint64 // Увеличивается при каждой смене состояния
+		transitions      []CircuitState
+	}
+
+	// circuitToken - разрешение allow на выполнение запроса, передаваемое в done
+	circuitToken struct {
+		generation uint64 // circuitBreaker.generation на момент разрешения
+		probe      bool   // Пробный запрос в CircuitHalfOpen
+	}
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+func newCircuitBreaker(settings CircuitBreakerSettings) *circuitBreaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = defaultFailureThreshold
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = defaultOpenTimeout
+	}
+	if settings.HalfOpenMaxRequests <= 0 {
+		settings.HalfOpenMaxRequests = defaultHalfOpenMaxRequests
+	}
+
+	return &circuitBreaker{settings: settings}
+}
+
+// get возвращает выключатель для операции op либо nil, если выключатель не настроен
+func (cb *circuitBreakers) get(op Operation) *circuitBreaker {
+	if cb == nil {
+		return nil
+	}
+	if b, ok := cb.byOperation[op]; ok {
+		return b
+	}
+	return cb.fallback
+}
+
+// CircuitState возвращает текущее состояние автоматического выключателя для операции op. Если выключатель не
+// настроен, возвращает CircuitClosed.
+func (a *Client) CircuitState(op Operation) CircuitState {
+	b := a.breakers.get(op)
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.settings.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow проверяет, можно ли выполнить запрос. Если запрос разрешен, после его выполнения необходимо вызвать done с
+// полученным circuitToken.
+func (b *circuitBreaker) allow(op Operation) (circuitToken, error) {
+	var changes []CircuitState
+	defer func() { b.notify(op, changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.state
+	defer func() { changes = b.changes(from) }()
+
+	if b.state == CircuitOpen {
+		if time.Since(b.openedAt) < b.settings.OpenTimeout {
+			return circuitToken{}, ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+	}
+
+	token := circuitToken{generation: b.generation}
+	if b.state == CircuitHalfOpen {
+		if b.halfOpenInFlight >= b.settings.HalfOpenMaxRequests {
+			return circuitToken{}, ErrCircuitOpen
+		}
+		b.halfOpenInFlight++
+		token.probe = true
+	}
+
+	return token, nil
+}
+
+// done учитывает результат запроса, разрешенного allow. Результаты запросов, разрешенных до последней смены состояния
+// (например, медленного запроса, начатого в CircuitClosed и завершенного в CircuitHalfOpen), не учитываются: состояние
+// определяют только запросы текущего состояния, а в CircuitHalfOpen - только пробные.
+func (b *circuitBreaker) done(ctx context.Context, op Operation, token circuitToken, err error) {
+	var changes []CircuitState
+	defer func() { b.notify(op, changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.state
+	defer func() { changes = b.changes(from) }()
+
+	if token.generation != b.generation {
+		return
+	}
+
+	halfOpen := token.probe
+	if halfOpen {
+		b.halfOpenInFlight--
+	}
+
+	switch {
+	case isTransientError(err):
+		b.failures++
+		if halfOpen || b.failures >= b.settings.FailureThreshold {
+			b.openedAt = time.Now()
+			b.setState(CircuitOpen)
+		}
+	case err != nil && ctx.Err() != nil:
+		// Запрос отменен вызывающей стороной, результат неизвестен
+	default:
+		b.failures = 0
+		if halfOpen {
+			b.setState(CircuitClosed)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+
+	b.state = state
+	b.generation++
+	b.transitions = append(b.transitions, state)
+	if state != CircuitHalfOpen {
+		b.halfOpenInFlight = 0
+	}
+	if state == CircuitClosed {
+		b.failures = 0
+	}
+}
+
+// changes возвращает последовательность состояний, начиная с from, через которые прошел выключатель с момента
+// последнего вызова. Вызывается под b.mu.
+func (b *circuitBreaker) changes(from CircuitState) []CircuitState {
+	if len(b.transitions) == 0 {
+		return nil
+	}
+
+	changes := append([]CircuitState{from}, b.transitions...)
+	b.transitions = b.transitions[:0]
+	return changes
+}
+
+// notify вызывает OnStateChange для каждой смены состояния вне блокировки b.mu
+func (b *circuitBreaker) notify(op Operation, changes []CircuitState) {
+	if b.settings.OnStateChange == nil || len(changes) == 0 {
+		return
+	}
+
+	b.notifyMu.Lock()
+	defer b.notifyMu.Unlock()
+	for i := 1; i < len(changes); i++ {
+		b.settings.OnStateChange(op, changes[i-1], changes[i])
+	}
+}
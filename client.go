@@ -1,29 +1,65 @@
 package oacquiring
 
-import "net/http"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// OperationCreatePayment - операция Client.CreatePayment
+	OperationCreatePayment Operation = "CreatePayment"
+	// OperationGetPaymentInfo - операция Client.GetPaymentInfo
+	OperationGetPaymentInfo Operation = "GetPaymentInfo"
+	// OperationReversePayment - операция Client.ReversePayment
+	OperationReversePayment Operation = "ReversePayment"
+	// OperationGetPaymentsOnShift - операция Client.GetPaymentsOnShift
+	OperationGetPaymentsOnShift Operation = "GetPaymentsOnShift"
+	// OperationCreatePOSPayment - операция Client.CreatePOSPayment
+	OperationCreatePOSPayment Operation = "CreatePOSPayment"
+	// OperationCancelPayment - операция Client.CancelPayment
+	OperationCancelPayment Operation = "CancelPayment"
+)
 
 type (
 	// Client - клиент для использования API. Для инициализации используйте NewClient
 	Client struct {
 		baseUrl string
 
-		cashboxRegNumber string
-		cashboxPassword  string
+		credentials CredentialsProvider
 
 		httpClient http.Client
+
+		hooks       []ClientHooks
+		retry       RetryPolicy
+		middlewares []Middleware
+		limiters    *rateLimiters
+		breakers    *circuitBreakers
 	}
+
+	// Operation - название операции API, выполняемой Client. Например, OperationCreatePayment
+	Operation string
 )
 
 // NewClient возвращает новый Client.
-//   - baseUrl - Базовый URL сервера Оплати, например https://oplati-cashboxapi.lwo-dev.by/ms-pay
+//   - baseUrl - Базовый URL сервера Оплати, например BaseUrlSandbox или BaseUrlProduction
 //   - cashboxRegNumber - Регистрационный номер кассы, например OPL000011111
 //   - cashboxPassword - Пароль для интернет-кассы
-//   - opts - Дополнительные настройки: WithCustomHTTPClient
+//   - opts - Дополнительные настройки: WithCustomHTTPClient, WithClientHooks, WithCredentialsProvider, WithRetry,
+//     WithMiddleware, WithRateLimit, WithCircuitBreaker
+//
+// Если указан WithCredentialsProvider, cashboxRegNumber и cashboxPassword не используются и могут быть пустыми.
 func NewClient(baseUrl, cashboxRegNumber, cashboxPassword string, opts ...ClientOpt) Client {
 	c := Client{
-		baseUrl:          baseUrl,
-		cashboxRegNumber: cashboxRegNumber,
-		cashboxPassword:  cashboxPassword,
+		baseUrl:     baseUrl,
+		credentials: StaticCredentials(cashboxRegNumber, cashboxPassword),
 	}
 
 	for _, opt := range opts {
@@ -32,3 +68,113 @@ func NewClient(baseUrl, cashboxRegNumber, cashboxPassword string, opts ...Client
 
 	return c
 }
+
+// getCredentials возвращает Credentials для очередного запроса
+func (a *Client) getCredentials(ctx context.Context) (Credentials, error) {
+	creds, err := a.credentials.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials retrieval failed: %w", err)
+	}
+	return creds, nil
+}
+
+// do выполняет запрос к API Оплати от имени кассы creds. Если request не nil, он кодируется в json и отправляется в
+// теле запроса. При ответе 200 OK тело ответа декодируется в response, в противном случае возвращается *ServerError.
+// Запросы повторяются согласно RetryPolicy.
+func (a *Client) do(ctx context.Context, creds Credentials, op Operation, method, path string, request, response any) error {
+	var body []byte
+	if request != nil {
+		var err error
+		body, err = json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("request encoding failed: %w", err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := a.doOnce(ctx, creds, op, method, path, body, response)
+		if err == nil || !a.retry.shouldRetry(ctx, op, method, attempt, err) {
+			return err
+		}
+
+		delay := a.retry.backoff(attempt)
+		if serverErr := (*ServerError)(nil); errors.As(err, &serverErr) {
+			delay = max(delay, serverErr.RetryAfter())
+		}
+
+		if !sleep(ctx, delay) {
+			return err
+		}
+	}
+}
+
+// doOnce выполняет одну попытку запроса к API Оплати
+func (a *Client) doOnce(ctx context.Context, creds Credentials, op Operation, method, path string, body []byte, response any) (err error) {
+	// Выключатель проверяется после ожидания ограничителя, чтобы пробный запрос в CircuitHalfOpen не занимал слот,
+	// пока ждет своей очереди
+	if l := a.limiters.get(op); l != nil {
+		waitStart := time.Now()
+		err = l.wait(ctx)
+		a.rateLimitWaited(ctx, op, time.Since(waitStart), err)
+		if err != nil {
+			return fmt.Errorf("waiting for rate limiter failed: %w", err)
+		}
+		defer l.release()
+	}
+
+	if b := a.breakers.get(op); b != nil {
+		token, allowErr := b.allow(op)
+		if allowErr != nil {
+			err = fmt.Errorf("%s: %w", op, allowErr)
+			a.requestRejected(ctx, op, err)
+			return err
+		}
+		defer func() { b.done(ctx, op, token, err) }()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	// connected показывает, было ли установлено соединение, т.е. мог ли запрос быть отправлен
+	var connected atomic.Bool
+	reqCtx := httptrace.WithClientTrace(contextWithOperation(ctx, op), &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { connected.Store(true) },
+	})
+
+	r, err := http.NewRequestWithContext(reqCtx, method, a.baseUrl+path, bodyReader)
+	if err != nil {
+		return fmt.Errorf("request initialization failed: %w", err)
+	}
+
+	r.Header.Set("RegNum", creds.RegNum)
+	r.Header.Set("Password", creds.Password)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	defer func() { a.requestDone(ctx, op, time.Since(start), err) }()
+
+	resp, err := a.doer().Do(r)
+	if err != nil {
+		return &executionError{err: err, beforeSend: !connected.Load() && isConnectError(err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if l := a.limiters.get(op); l != nil {
+		l.observe(resp)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newServerError(resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(response)
+	if err != nil {
+		return fmt.Errorf("decoding response failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/oplati-by/go-acquiring/audit"
+)
+
+// auditOutput - результат проверки журнала аудита в формате json
+type auditOutput struct {
+	Valid   bool   `json:"valid"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+func runAuditVerify(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "audit-verify", "<audit.jsonl>")
+	anchor := fs.String("anchor", "", "номер и хеш записи, сохраненные вне журнала (audit.Log.Head), в виде SEQ:HASH")
+	if err := parseFlags(fs, common, args, 1); err != nil {
+		return err
+	}
+
+	var opts []audit.VerifyOpt
+	if key := os.Getenv(envPrefix + "AUDIT_KEY"); key != "" {
+		opts = append(opts, audit.WithVerifyKey([]byte(key)))
+	}
+	if *anchor != "" {
+		seqText, hash, ok := strings.Cut(*anchor, ":")
+		seq, err := strconv.ParseUint(seqText, 10, 64)
+		if !ok || err != nil || hash == "" {
+			return &usageError{err: fmt.Errorf("invalid anchor %q, expected SEQ:HASH", *anchor)}
+		}
+		opts = append(opts, audit.WithAnchor(seq, hash))
+	}
+
+	backend, err := audit.NewFile(fs.Arg(0), audit.WithReadOnly())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = backend.Close() }()
+
+	count, verifyErr := audit.Verify(ctx, backend, opts...)
+	result := auditOutput{Valid: verifyErr == nil, Records: count}
+	if verifyErr != nil {
+		result.Error = verifyErr.Error()
+	}
+
+	if common.output == outputJSON {
+		err = writeJSON(env.stdout, result)
+	} else {
+		_, err = fmt.Fprintf(env.stdout, "%d record(s) verified\n", count)
+	}
+	if err != nil {
+		return err
+	}
+
+	if verifyErr != nil {
+		if audit.IsVerificationError(verifyErr) {
+			return &exitCodeError{code: exitAuditInvalid, err: verifyErr}
+		}
+		return verifyErr
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/oplati-by/go-acquiring/capture"
+)
+
+func runCapture(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "capture", "")
+	listen := fs.String("listen", "localhost:8080", "адрес для приема уведомлений")
+	key := fs.String("key", "", "публичный ключ Оплати (base64); по умолчанию notificationPublicKey из настроек")
+	forward := fs.String("forward", "", "URL настоящего обработчика уведомлений")
+	dir := fs.String("dir", "", "каталог для сохранения доставок; по умолчанию доставки хранятся в памяти")
+	if err := parseFlags(fs, common, args, 0); err != nil {
+		return err
+	}
+
+	publicKey := *key
+	if publicKey == "" {
+		cfg, err := common.config()
+		if err != nil {
+			return &usageError{err: err}
+		}
+		publicKey = cfg.NotificationPublicKey
+	}
+
+	opts := []capture.Opt{capture.WithErrorHandler(func(err error) { _, _ = fmt.Fprintln(env.stderr, err) })}
+	if *dir != "" {
+		store, err := capture.NewDirStore(*dir)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, capture.WithStore(store))
+	}
+
+	var handler http.Handler
+	if *forward != "" {
+		handler = capture.ForwardHandler(*forward, &http.Client{Timeout: common.timeout})
+	}
+
+	server, err := capture.New(publicKey, handler, opts...)
+	if err != nil {
+		return &usageError{err: err}
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(env.stderr, "Receiving notifications at http://%s/\n", listener.Addr())
+	_, _ = fmt.Fprintf(env.stderr, "Deliveries: http://%s%s\n", listener.Addr(), capture.UIPrefix)
+	if publicKey == "" {
+		_, _ = fmt.Fprintln(env.stderr, "Public key is not configured, signatures are not verified")
+	}
+
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	err = httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+func runCreate(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "create", "")
+	file := fs.String("f", "-", "json файл с платежом, - для stdin")
+	pos := fs.Bool("pos", false, "создать платеж на кассе магазина (динамический QR код)")
+	if err := parseFlags(fs, common, args, 0); err != nil {
+		return err
+	}
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(env, *file)
+	if err != nil {
+		return fmt.Errorf("input reading failed: %w", err)
+	}
+	var input paymentInput
+	if err = decodeInput(data, &input); err != nil {
+		return &usageError{err: err}
+	}
+
+	if *pos {
+		created, err := client.CreatePOSPayment(ctx, input.posPayment())
+		if err != nil {
+			return err
+		}
+		return writeCreated(env.stdout, common.output, createdOutput{
+			PaymentId: created.PaymentId,
+			Status:    created.Status.String(),
+			QRData:    created.QRData,
+		})
+	}
+
+	created, err := client.CreatePayment(ctx, input.payment())
+	if err != nil {
+		return err
+	}
+	return writeCreated(env.stdout, common.output, createdOutput{
+		PaymentId:   created.PaymentId,
+		RedirectUrl: created.RedirectUrl,
+	})
+}
+
+func runStatus(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "status", "<paymentId>")
+	if err := parseFlags(fs, common, args, 1); err != nil {
+		return err
+	}
+
+	paymentId, err := parsePaymentId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+
+	info, err := client.GetPaymentInfo(ctx, paymentId)
+	if err != nil {
+		return err
+	}
+	return writePayment(env.stdout, common.output, info)
+}
+
+func runWait(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "wait", "<paymentId>")
+	interval := fs.Duration("interval", 2*time.Second, "интервал опроса статуса")
+	maxWait := fs.Duration("max-wait", 0, "максимальное время ожидания, 0 - без ограничения")
+	if err := parseFlags(fs, common, args, 1); err != nil {
+		return err
+	}
+
+	paymentId, err := parsePaymentId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+
+	if *maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *maxWait)
+		defer cancel()
+	}
+
+	info, err := client.WaitPayment(ctx, paymentId, *interval)
+	if err != nil {
+		return err
+	}
+
+	err = writePayment(env.stdout, common.output, info)
+	if err != nil {
+		return err
+	}
+
+	if info.Status != oacquiring.PaymentStatusDone {
+		return &exitCodeError{
+			code: exitPaymentFailed,
+			err:  fmt.Errorf("payment %d finished with status %s", paymentId, info.Status),
+		}
+	}
+	return nil
+}
+
+func runReverse(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "reverse", "<paymentId>")
+	file := fs.String("f", "-", "json файл с возвратом, - для stdin")
+	if err := parseFlags(fs, common, args, 1); err != nil {
+		return err
+	}
+
+	paymentId, err := parsePaymentId(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(env, *file)
+	if err != nil {
+		return fmt.Errorf("input reading failed: %w", err)
+	}
+	var input reversalInput
+	if err = decodeInput(data, &input); err != nil {
+		return &usageError{err: err}
+	}
+
+	info, err := client.ReversePayment(ctx, paymentId, input.reversal())
+	if err != nil {
+		return err
+	}
+	return writePayment(env.stdout, common.output, info)
+}
+
+func runShift(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "shift", "<shift>")
+	lenient := fs.Bool("lenient", false, "пропускать платежи, которые не удалось разобрать, и выводить ошибки в stderr")
+	if err := parseFlags(fs, common, args, 1); err != nil {
+		return err
+	}
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+
+	if !*lenient {
+		payments, err := client.GetPaymentsOnShift(ctx, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		return writeShift(env.stdout, common.output, fs.Arg(0), payments)
+	}
+
+	payments, decodeErrors, err := client.GetPaymentsOnShiftLenient(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, decodeErr := range decodeErrors {
+		_, _ = fmt.Fprintf(env.stderr, "%v: %s\n", decodeErr, decodeErr.Raw)
+	}
+	return writeShift(env.stdout, common.output, fs.Arg(0), payments)
+}
+
+func parsePaymentId(value string) (int64, error) {
+	paymentId, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &usageError{err: fmt.Errorf("bad payment id %q", value)}
+	}
+	return paymentId, nil
+}
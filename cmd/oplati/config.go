@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// envPrefix - префикс переменных окружения с настройками
+const envPrefix = "OPLATI_"
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type (
+	// commonFlags - флаги, общие для всех команд
+	commonFlags struct {
+		configFile    string
+		env           string
+		baseUrl       string
+		regNum        string
+		passwordStdin bool
+		timeout       time.Duration
+		output        string
+
+		streams  *environment
+		password string // Пароль, прочитанный из stdin
+	}
+)
+
+// newFlagSet возвращает flag.FlagSet команды name с общими флагами
+func newFlagSet(env *environment, name, arguments string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+
+	common := &commonFlags{streams: env}
+	fs.StringVar(&common.configFile, "config", os.Getenv(envPrefix+"CONFIG"), "yaml или json файл с настройками")
+	fs.StringVar(&common.env, "env", "", "окружение: sandbox или production")
+	fs.StringVar(&common.baseUrl, "base-url", "", "базовый URL сервера Оплати")
+	fs.StringVar(&common.regNum, "reg-num", "", "регистрационный номер кассы")
+	fs.BoolVar(&common.passwordStdin, "password-stdin", false,
+		"прочитать пароль кассы из stdin (вместо переменной "+envPrefix+"PASSWORD)")
+	fs.DurationVar(&common.timeout, "timeout", 0, "таймаут запроса к серверу Оплати")
+	fs.StringVar(&common.output, "o", outputTable, "формат вывода: table или json")
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "%s\n\nFlags:\n", strings.TrimSpace("Usage: oplati "+name+" [flags] "+arguments))
+		fs.PrintDefaults()
+	}
+
+	return fs, common
+}
+
+// parseFlags разбирает args и проверяет количество позиционных аргументов
+func parseFlags(fs *flag.FlagSet, common *commonFlags, args []string, positional int) error {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return &exitCodeError{code: exitOK, err: err}
+	}
+	if err != nil {
+		return &usageError{err: err}
+	}
+
+	if fs.NArg() != positional {
+		fs.Usage()
+		return &usageError{err: fmt.Errorf("expected %d argument(s), got %d", positional, fs.NArg())}
+	}
+
+	if common.output != outputTable && common.output != outputJSON {
+		return &usageError{err: fmt.Errorf("unsupported output format %q", common.output)}
+	}
+
+	return nil
+}
+
+// config собирает oacquiring.Config из файла, переменных окружения и флагов
+func (f *commonFlags) config() (oacquiring.Config, error) {
+	var cfg oacquiring.Config
+
+	if f.passwordStdin {
+		password, err := readInput(f.streams, "-")
+		if err != nil {
+			return oacquiring.Config{}, fmt.Errorf("password reading failed: %w", err)
+		}
+		f.password = strings.TrimRight(string(password), "\r\n")
+		f.passwordStdin = false
+	}
+
+	if f.configFile != "" {
+		fileCfg, err := oacquiring.LoadConfigFile(f.configFile)
+		if err != nil {
+			return oacquiring.Config{}, err
+		}
+		cfg = fileCfg
+	}
+
+	envCfg, err := oacquiring.LoadConfigFromEnv(envPrefix)
+	if err != nil {
+		return oacquiring.Config{}, err
+	}
+	mergeConfig(&cfg, envCfg)
+
+	mergeConfig(&cfg, oacquiring.Config{
+		Environment: f.env,
+		BaseUrl:     f.baseUrl,
+		RegNum:      f.regNum,
+		Password:    f.password,
+		Timeout:     oacquiring.Duration(f.timeout),
+	})
+
+	return cfg, nil
+}
+
+// client возвращает oacquiring.Client, настроенный по флагам
+func (f *commonFlags) client() (*oacquiring.Client, error) {
+	cfg, err := f.config()
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+
+	client, err := oacquiring.NewClientFromConfig(cfg)
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+
+	return &client, nil
+}
+
+// mergeConfig переносит в dst непустые поля src
+func mergeConfig(dst *oacquiring.Config, src oacquiring.Config) {
+	if src.Environment != "" {
+		dst.Environment = src.Environment
+	}
+	if src.BaseUrl != "" {
+		dst.BaseUrl = src.BaseUrl
+	}
+	if src.RegNum != "" {
+		dst.RegNum = src.RegNum
+	}
+	if src.Password != "" {
+		dst.Password = src.Password
+	}
+	if src.CredentialsFile != "" {
+		dst.CredentialsFile = src.CredentialsFile
+	}
+	if src.Timeout != 0 {
+		dst.Timeout = src.Timeout
+	}
+	if src.Retry.MaxAttempts != 0 {
+		dst.Retry.MaxAttempts = src.Retry.MaxAttempts
+	}
+	if src.Retry.InitialBackoff != 0 {
+		dst.Retry.InitialBackoff = src.Retry.InitialBackoff
+	}
+	if src.Retry.MaxBackoff != 0 {
+		dst.Retry.MaxBackoff = src.Retry.MaxBackoff
+	}
+	if src.NotificationPublicKey != "" {
+		dst.NotificationPublicKey = src.NotificationPublicKey
+	}
+}
+
+// readInput читает файл path или stdin, если path пустой или "-". stdin может быть прочитан только один раз.
+func readInput(env *environment, path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		if env.stdinUsed {
+			return nil, errors.New("stdin is already read: -password-stdin cannot be combined with input from stdin")
+		}
+		env.stdinUsed = true
+		return io.ReadAll(env.stdin)
+	}
+	return os.ReadFile(path)
+}
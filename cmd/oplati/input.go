@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+type (
+	// paymentInput - платеж в формате json:
+	//
+	//	{
+	//	  "shift": "01012025",
+	//	  "orderNumber": "123",
+	//	  "items": [{"type": "product", "name": "Товар", "cost": 545}],
+	//	  "receiptFooterText": "",
+	//	  "successUrl": "",
+	//	  "failureUrl": "",
+	//	  "notificationUrl": ""
+	//	}
+	paymentInput struct {
+		Shift             string      `json:"shift"`
+		OrderNumber       string      `json:"orderNumber"`
+		Items             []itemInput `json:"items"`
+		ReceiptFooterText string      `json:"receiptFooterText"`
+		SuccessUrl        string      `json:"successUrl"`
+		FailureUrl        string      `json:"failureUrl"`
+		NotificationUrl   string      `json:"notificationUrl"`
+	}
+
+	// reversalInput - возврат в формате json, поля совпадают с paymentInput без URL
+	reversalInput struct {
+		Shift             string      `json:"shift"`
+		OrderNumber       string      `json:"orderNumber"`
+		Items             []itemInput `json:"items"`
+		ReceiptFooterText string      `json:"receiptFooterText"`
+	}
+
+	// itemInput - позиция в чеке. Стоимость указывается в копейках
+	itemInput struct {
+		Type itemType `json:"type"`
+		Name string   `json:"name"`
+		Cost int64    `json:"cost"`
+	}
+
+	// itemType - тип позиции: "product", "service" или числовое значение oacquiring.PaymentItemType
+	itemType oacquiring.PaymentItemType
+)
+
+// UnmarshalJSON реализует json.Unmarshaler
+func (t *itemType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var value int
+		if err = json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("item type should be a string or a number: %w", err)
+		}
+		*t = itemType(value)
+		return nil
+	}
+
+	switch strings.ToLower(name) {
+	case "product":
+		*t = itemType(oacquiring.PaymentItemTypeProduct)
+	case "service":
+		*t = itemType(oacquiring.PaymentItemTypeService)
+	default:
+		return fmt.Errorf("unknown item type %q", name)
+	}
+	return nil
+}
+
+// decodeInput разбирает json из data в v. Неизвестные поля считаются ошибкой.
+func decodeInput(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		return fmt.Errorf("input decoding failed: %w", err)
+	}
+	return nil
+}
+
+func (p paymentInput) payment() oacquiring.Payment {
+	return oacquiring.Payment{
+		Shift:             p.Shift,
+		OrderNumber:       p.OrderNumber,
+		Items:             makeItems(p.Items),
+		ReceiptFooterText: p.ReceiptFooterText,
+		SuccessUrl:        p.SuccessUrl,
+		FailureUrl:        p.FailureUrl,
+		NotificationUrl:   p.NotificationUrl,
+	}
+}
+
+func (p paymentInput) posPayment() oacquiring.POSPayment {
+	return oacquiring.POSPayment{
+		Shift:             p.Shift,
+		OrderNumber:       p.OrderNumber,
+		Items:             makeItems(p.Items),
+		ReceiptFooterText: p.ReceiptFooterText,
+	}
+}
+
+func (r reversalInput) reversal() oacquiring.PaymentReversal {
+	return oacquiring.PaymentReversal{
+		Shift:             r.Shift,
+		OrderNumber:       r.OrderNumber,
+		Items:             makeItems(r.Items),
+		ReceiptFooterText: r.ReceiptFooterText,
+	}
+}
+
+func makeItems(items []itemInput) []oacquiring.PaymentItem {
+	result := make([]oacquiring.PaymentItem, len(items))
+	for i, item := range items {
+		result[i] = oacquiring.PaymentItem{
+			Type: oacquiring.PaymentItemType(item.Type),
+			Name: item.Name,
+			Cost: item.Cost,
+		}
+	}
+	return result
+}
@@ -0,0 +1,210 @@
+// Команда oplati выполняет операции кассы Оплати из командной строки: создание платежа, проверку статуса, ожидание
+// оплаты, возврат и получение отчета по смене.
+//
+// Использование:
+//
+//	oplati <команда> [флаги] [аргументы]
+//
+// Команды:
+//
+//	create  [-pos] [-f payment.json]            создать платеж (json из файла или stdin)
+//	status  <paymentId>                          получить статус платежа
+//	wait    [-interval 2s] <paymentId>           дождаться окончательного статуса платежа
+//	reverse [-f reversal.json] <paymentId>       выполнить возврат (json из файла или stdin)
+//	shift   [-lenient] <shift>                   получить список платежей за смену
+//	verify  [-key KEY] (-request FILE | -f FILE -sign SIGN)
+//	                                             проверить подпись уведомления и объяснить ошибку
+//	replay  -url URL [-private-key PEM] (-request FILE | -f FILE -sign SIGN)
+//	                                             отправить сохраненное уведомление обработчику
+//	sign    -private-key PEM [-request] (-f FILE | -payment-id ID [-status N] [-sum N] [-order N])
+//	                                             подписать уведомление тестовым ключом
+//	keygen  <private-key.pem>                    создать тестовый ключ и вывести публичный ключ
+//	capture [-listen ADDR] [-key KEY] [-forward URL] [-dir DIR]
+//	                                             принимать уведомления, сохранять доставки и показывать их по
+//	                                             адресу /_capture/ (см. пакет capture)
+//	audit-verify [-anchor SEQ:HASH] <audit.jsonl>
+//	                                             проверить целостность файла журнала аудита (см. пакет audit);
+//	                                             ключ HMAC берется из переменной OPLATI_AUDIT_KEY
+//
+// verify, replay и sign используют проверку и подпись уведомлений пакета oacquiring. Сохраненное уведомление
+// (-request) - HTTP запрос в формате httputil.DumpRequest, например результат oplati sign -request.
+//
+// Настройки подключения берутся (в порядке убывания приоритета) из флагов -base-url, -env, -reg-num,
+// -password-stdin, -timeout, переменных окружения OPLATI_* (см. oacquiring.LoadConfigFromEnv) и файла из флага
+// -config или переменной OPLATI_CONFIG. Пароль не передается аргументом командной строки, т.к. аргументы видны другим
+// пользователям в списке процессов: используйте OPLATI_PASSWORD, файл настроек или -password-stdin. Флаг -o json
+// включает вывод в формате json вместо таблицы.
+//
+// Коды завершения:
+//
+//	0   успешно
+//	1   прочие ошибки (сеть, чтение файлов, разбор ответа)
+//	2   неверные аргументы или настройки
+//	3   wait: платеж завершился со статусом, отличным от OK
+//	4   verify: подпись уведомления неверна
+//	5   replay: обработчик ответил кодом, отличным от 2xx
+//	6   audit-verify: журнал изменен или в нем отсутствуют записи
+//	30  сервер Оплати отклонил запрос (HTTP 4xx, кроме 429)
+//	31  временная ошибка сервера Оплати (HTTP 429 или 5xx), запрос можно повторить позже
+//
+// Коды завершения зависят только от HTTP кода ответа: внутренний код ошибки (ServerError.InternalCode) выводится в
+// сообщении об ошибке.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitUsage            = 2
+	exitPaymentFailed    = 3
+	exitSignatureInvalid = 4
+	exitReplayFailed     = 5
+	exitAuditInvalid     = 6
+	exitServerError      = 30
+	exitServerRetryable  = 31
+)
+
+type (
+	// command - подкоманда oplati
+	command struct {
+		name        string
+		description string
+		run         func(ctx context.Context, env *environment, args []string) error
+	}
+
+	// environment - стандартные потоки команды
+	environment struct {
+		stdin  io.Reader
+		stdout io.Writer
+		stderr io.Writer
+
+		stdinUsed bool // stdin уже прочитан, например флагом -password-stdin
+	}
+
+	// usageError - ошибка в аргументах командной строки
+	usageError struct {
+		err error
+	}
+
+	// exitCodeError - ошибка с заданным кодом завершения
+	exitCodeError struct {
+		code int
+		err  error
+	}
+)
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "create", description: "создать платеж", run: runCreate},
+		{name: "status", description: "получить статус платежа", run: runStatus},
+		{name: "wait", description: "дождаться окончательного статуса платежа", run: runWait},
+		{name: "reverse", description: "выполнить возврат", run: runReverse},
+		{name: "shift", description: "получить список платежей за смену", run: runShift},
+		{name: "verify", description: "проверить подпись сохраненного уведомления", run: runVerify},
+		{name: "replay", description: "повторно отправить уведомление обработчику", run: runReplay},
+		{name: "sign", description: "подписать уведомление тестовым ключом", run: runSign},
+		{name: "keygen", description: "создать тестовую пару ключей", run: runKeygen},
+		{name: "capture", description: "принимать и сохранять уведомления для отладки", run: runCapture},
+		{name: "audit-verify", description: "проверить целостность файла журнала аудита", run: runAuditVerify},
+	}
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, &environment{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, env *environment, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		printUsage(env.stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		err := cmd.run(ctx, env, args[1:])
+		if err == nil {
+			return exitOK
+		}
+
+		code := exitCode(err)
+		if code != exitOK {
+			_, _ = fmt.Fprintf(env.stderr, "oplati %s: %s\n", cmd.name, err)
+		}
+		return code
+	}
+
+	_, _ = fmt.Fprintf(env.stderr, "oplati: unknown command %q\n\n", args[0])
+	printUsage(env.stderr)
+	return exitUsage
+}
+
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: oplati <command> [flags] [arguments]")
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.description)
+	}
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Run 'oplati <command> -h' for command flags.")
+}
+
+// exitCode возвращает код завершения для ошибки команды
+func exitCode(err error) int {
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	var codeErr *exitCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.code
+	}
+
+	var serverErr *oacquiring.ServerError
+	if errors.As(err, &serverErr) {
+		if serverErr.Retryable() {
+			return exitServerRetryable
+		}
+		return exitServerError
+	}
+
+	return exitError
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+type (
+	// notificationFlags - флаги, задающие уведомление: сохраненный HTTP запрос или тело и подпись
+	notificationFlags struct {
+		request   string
+		body      string
+		signature string
+	}
+
+	// notification - тело уведомления и значение заголовка Server-Sign
+	notification struct {
+		body      []byte
+		signature string
+	}
+
+	// verifyOutput - результат проверки уведомления в формате json
+	verifyOutput struct {
+		Valid   bool           `json:"valid"`
+		Error   string         `json:"error,omitempty"`
+		Hints   []string       `json:"hints,omitempty"`
+		Payment *paymentOutput `json:"payment,omitempty"`
+		Parse   string         `json:"parseError,omitempty"`
+	}
+
+	// signOutput - подписанное уведомление в формате json
+	signOutput struct {
+		Body      string `json:"body"`
+		Signature string `json:"signature"`
+	}
+
+	// replayOutput - ответ обработчика уведомлений в формате json
+	replayOutput struct {
+		Status int    `json:"status"`
+		Body   string `json:"body"`
+	}
+)
+
+func (f *notificationFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.request, "request", "", "файл с сохраненным HTTP запросом уведомления, - для stdin")
+	fs.StringVar(&f.body, "f", "", "файл с телом уведомления, - для stdin")
+	fs.StringVar(&f.signature, "sign", "", "значение заголовка "+oacquiring.ServerSignHeader)
+}
+
+// read возвращает уведомление из сохраненного HTTP запроса или из тела и подписи
+func (f *notificationFlags) read(env *environment) (notification, error) {
+	if f.request != "" {
+		if f.body != "" || f.signature != "" {
+			return notification{}, &usageError{err: errors.New("-request can not be combined with -f and -sign")}
+		}
+
+		data, err := readInput(env, f.request)
+		if err != nil {
+			return notification{}, fmt.Errorf("request reading failed: %w", err)
+		}
+		return parseCapturedRequest(data)
+	}
+
+	if f.body == "" {
+		return notification{}, &usageError{err: errors.New("-request or -f should be specified")}
+	}
+
+	body, err := readInput(env, f.body)
+	if err != nil {
+		return notification{}, fmt.Errorf("body reading failed: %w", err)
+	}
+
+	return notification{body: body, signature: f.signature}, nil
+}
+
+// parseCapturedRequest разбирает HTTP запрос в формате httputil.DumpRequest
+func parseCapturedRequest(data []byte) (notification, error) {
+	reader := bufio.NewReader(bytes.NewReader(data))
+	req, err := http.ReadRequest(reader)
+	if err != nil {
+		return notification{}, fmt.Errorf("request parsing failed: %w", err)
+	}
+	defer func() { _ = req.Body.Close() }()
+
+	// Без Content-Length и Transfer-Encoding телом считается остаток файла
+	bodyReader := io.Reader(req.Body)
+	if req.ContentLength <= 0 && len(req.TransferEncoding) == 0 {
+		bodyReader = reader
+	}
+
+	body, err := io.ReadAll(bodyReader)
+	if err != nil {
+		return notification{}, fmt.Errorf("request body reading failed: %w", err)
+	}
+
+	return notification{body: body, signature: req.Header.Get(oacquiring.ServerSignHeader)}, nil
+}
+
+func runVerify(_ context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "verify", "")
+	var input notificationFlags
+	input.register(fs)
+	key := fs.String("key", "", "публичный ключ Оплати (base64); по умолчанию notificationPublicKey из настроек")
+	if err := parseFlags(fs, common, args, 0); err != nil {
+		return err
+	}
+
+	publicKey, err := loadPublicKey(common, *key)
+	if err != nil {
+		return err
+	}
+
+	n, err := input.read(env)
+	if err != nil {
+		return err
+	}
+
+	result := verifyOutput{}
+	verifyErr := oacquiring.VerifyNotification(publicKey, n.body, n.signature)
+	if verifyErr == nil {
+		result.Valid = true
+	} else {
+		result.Error = verifyErr.Error()
+		result.Hints = diagnoseSignature(publicKey, n.body, n.signature, verifyErr)
+	}
+
+	payment, err := oacquiring.ParseNotification(n.body)
+	if err != nil {
+		result.Parse = err.Error()
+	} else {
+		output := newPaymentOutput(payment)
+		result.Payment = &output
+	}
+
+	if err = writeVerify(env.stdout, common.output, result); err != nil {
+		return err
+	}
+
+	if verifyErr != nil {
+		return &exitCodeError{code: exitSignatureInvalid, err: verifyErr}
+	}
+	return nil
+}
+
+// diagnoseSignature объясняет, почему подпись не прошла проверку
+func diagnoseSignature(publicKey *rsa.PublicKey, body []byte, signature string, verifyErr error) []string {
+	switch {
+	case errors.Is(verifyErr, oacquiring.ErrSignatureMissing):
+		return []string{"the " + oacquiring.ServerSignHeader + " header is empty: check that a proxy does not strip it"}
+
+	case errors.Is(verifyErr, oacquiring.ErrSignatureEncoding):
+		trimmed := strings.TrimSpace(signature)
+		if trimmed != signature && oacquiring.VerifyNotification(publicKey, body, trimmed) == nil {
+			return []string{"the signature has leading or trailing whitespace; it is valid once trimmed"}
+		}
+		if _, err := base64.URLEncoding.DecodeString(trimmed); err == nil {
+			return []string{"the signature is URL-safe base64, standard base64 is expected"}
+		}
+		if _, err := base64.RawStdEncoding.DecodeString(trimmed); err == nil {
+			return []string{"the signature has no base64 padding: it was probably truncated"}
+		}
+		return []string{"the signature is not base64: check that the header value was copied completely"}
+	}
+
+	var hints []string
+
+	decoded, _ := base64.StdEncoding.DecodeString(signature)
+	if len(decoded) != publicKey.Size() {
+		hints = append(hints, fmt.Sprintf("the signature is %d bytes, but the public key expects %d bytes: "+
+			"the signature was truncated or made with a key of another size", len(decoded), publicKey.Size()))
+	}
+
+	variants := []struct {
+		description string
+		body        []byte
+	}{
+		{"trailing whitespace or newline was added to the body", bytes.TrimRight(body, " \t\r\n")},
+		{"line endings of the body were converted to CRLF", bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))},
+		{"the body was reformatted (the signed body is compact json)", compactJSON(body)},
+	}
+	for _, variant := range variants {
+		if variant.body != nil && !bytes.Equal(variant.body, body) &&
+			oacquiring.VerifyNotification(publicKey, variant.body, signature) == nil {
+			return append(hints, variant.description+"; the signature is valid for the original body")
+		}
+	}
+
+	if len(hints) == 0 {
+		hints = append(hints, "the body was changed after signing or the notification was signed with another key: "+
+			"check the public key of this cashbox (sandbox and production keys differ)")
+	}
+	return hints
+}
+
+func compactJSON(body []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func writeVerify(w io.Writer, output string, result verifyOutput) error {
+	if output == outputJSON {
+		return writeJSON(w, result)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if result.Valid {
+		_, _ = fmt.Fprintln(tw, "Signature\tvalid")
+	} else {
+		_, _ = fmt.Fprintln(tw, "Signature\tINVALID")
+		_, _ = fmt.Fprintf(tw, "Error\t%s\n", result.Error)
+		for _, hint := range result.Hints {
+			_, _ = fmt.Fprintf(tw, "Hint\t%s\n", hint)
+		}
+	}
+
+	if result.Parse != "" {
+		_, _ = fmt.Fprintf(tw, "Body\t%s\n", result.Parse)
+	}
+	if result.Payment != nil {
+		p := result.Payment
+		_, _ = fmt.Fprintf(tw, "Payment ID\t%d\n", p.Id)
+		_, _ = fmt.Fprintf(tw, "Order number\t%s\n", p.OrderNumber)
+		_, _ = fmt.Fprintf(tw, "Status\t%s\n", p.Status)
+		_, _ = fmt.Fprintf(tw, "Sum\t%s\n", formatSum(p.Sum))
+	}
+
+	return tw.Flush()
+}
+
+func runReplay(ctx context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "replay", "")
+	var input notificationFlags
+	input.register(fs)
+	url := fs.String("url", "", "URL обработчика уведомлений, например http://localhost:8080/oplati/notification")
+	privateKeyFile := fs.String("private-key", "", "PEM файл тестового закрытого ключа для повторной подписи тела")
+	if err := parseFlags(fs, common, args, 0); err != nil {
+		return err
+	}
+	if *url == "" {
+		return &usageError{err: errors.New("-url should be specified")}
+	}
+
+	n, err := input.read(env)
+	if err != nil {
+		return err
+	}
+
+	if *privateKeyFile != "" {
+		privateKey, err := loadPrivateKey(*privateKeyFile)
+		if err != nil {
+			return err
+		}
+		n.signature, err = oacquiring.SignNotification(privateKey, n.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := newNotificationRequest(ctx, *url, n)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: common.timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request execution failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("response reading failed: %w", err)
+	}
+
+	result := replayOutput{Status: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	if common.output == outputJSON {
+		err = writeJSON(env.stdout, result)
+	} else {
+		_, err = fmt.Fprintf(env.stdout, "%s\n%s\n", resp.Status, result.Body)
+	}
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &exitCodeError{code: exitReplayFailed, err: fmt.Errorf("handler responded with %s", resp.Status)}
+	}
+	return nil
+}
+
+func runSign(_ context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "sign", "")
+	privateKeyFile := fs.String("private-key", "", "PEM файл тестового закрытого ключа (см. oplati keygen)")
+	bodyFile := fs.String("f", "-", "файл с телом уведомления, - для stdin. Не используется вместе с -payment-id")
+	asRequest := fs.Bool("request", false, "вывести HTTP запрос уведомления для oplati replay -request и oplati verify -request")
+	synthetic := oacquiring.PaymentInfo{Type: oacquiring.PaymentTypeSell, Status: oacquiring.PaymentStatusDone}
+	fs.Int64Var(&synthetic.Id, "payment-id", 0, "создать уведомление для платежа с этим идентификатором")
+	fs.StringVar(&synthetic.OrderNumber, "order", "", "номер заказа создаваемого уведомления")
+	fs.Int64Var(&synthetic.Sum, "sum", 100, "сумма создаваемого уведомления в копейках")
+	status := fs.Int("status", oacquiring.PaymentStatusDone, "статус создаваемого уведомления")
+	paymentType := fs.Int("type", oacquiring.PaymentTypeSell, "тип операции создаваемого уведомления")
+	if err := parseFlags(fs, common, args, 0); err != nil {
+		return err
+	}
+	if *privateKeyFile == "" {
+		return &usageError{err: errors.New("-private-key should be specified")}
+	}
+
+	privateKey, err := loadPrivateKey(*privateKeyFile)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if synthetic.Id != 0 {
+		now := time.Now().Truncate(time.Second)
+		synthetic.Status = oacquiring.PaymentStatus(*status)
+		synthetic.Type = oacquiring.PaymentType(*paymentType)
+		synthetic.CreatedDate, synthetic.PaidDate = now, now
+		body, err = oacquiring.MarshalNotification(synthetic)
+	} else {
+		body, err = readInput(env, *bodyFile)
+	}
+	if err != nil {
+		return fmt.Errorf("body preparation failed: %w", err)
+	}
+
+	signature, err := oacquiring.SignNotification(privateKey, body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *asRequest:
+		req, err := newNotificationRequest(context.Background(), "http://localhost/", notification{body: body, signature: signature})
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		dump, err := httputil.DumpRequest(req, true)
+		if err != nil {
+			return fmt.Errorf("request dumping failed: %w", err)
+		}
+		_, err = env.stdout.Write(dump)
+		return err
+	case common.output == outputJSON:
+		return writeJSON(env.stdout, signOutput{Body: string(body), Signature: signature})
+	default:
+		_, err = fmt.Fprintf(env.stdout, "%s: %s\n\n%s\n", oacquiring.ServerSignHeader, signature, body)
+		return err
+	}
+}
+
+func runKeygen(_ context.Context, env *environment, args []string) error {
+	fs, common := newFlagSet(env, "keygen", "<private-key.pem>")
+	bits := fs.Int("bits", 2048, "размер ключа в битах")
+	if err := parseFlags(fs, common, args, 1); err != nil {
+		return err
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, *bits)
+	if err != nil {
+		return fmt.Errorf("key generation failed: %w", err)
+	}
+
+	rawKey, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("key encoding failed: %w", err)
+	}
+
+	err = os.WriteFile(fs.Arg(0), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rawKey}), 0o600)
+	if err != nil {
+		return fmt.Errorf("key writing failed: %w", err)
+	}
+
+	publicKey, err := oacquiring.EncodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(env.stdout, publicKey)
+	return err
+}
+
+func newNotificationRequest(ctx context.Context, url string, n notification) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(n.body))
+	if err != nil {
+		return nil, fmt.Errorf("request initialization failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.signature != "" {
+		req.Header.Set(oacquiring.ServerSignHeader, n.signature)
+	}
+	return req, nil
+}
+
+// loadPublicKey возвращает публичный ключ из флага -key или из настроек
+func loadPublicKey(common *commonFlags, key string) (*rsa.PublicKey, error) {
+	if key == "" {
+		cfg, err := common.config()
+		if err != nil {
+			return nil, &usageError{err: err}
+		}
+		key = cfg.NotificationPublicKey
+	}
+	if key == "" {
+		return nil, &usageError{err: errors.New("-key or notificationPublicKey setting should be specified")}
+	}
+
+	publicKey, err := oacquiring.ParsePublicKey(strings.TrimSpace(key))
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+	return publicKey, nil
+}
+
+// loadPrivateKey читает закрытый RSA ключ из PEM файла в формате PKCS #8 или PKCS #1
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("private key reading failed: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key parsing failed: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("provided private key is not RSA")
+	}
+	return rsaKey, nil
+}
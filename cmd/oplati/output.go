@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+type (
+	// paymentOutput - PaymentInfo в формате json
+	paymentOutput struct {
+		Id            int64      `json:"id"`
+		Type          string     `json:"type"`
+		Status        string     `json:"status"`
+		Sum           int64      `json:"sum"`
+		OrderNumber   string     `json:"orderNumber"`
+		PursePublicId string     `json:"pursePublicId,omitempty"`
+		CreatedDate   *time.Time `json:"createdDate,omitempty"`
+		PaidDate      *time.Time `json:"paidDate,omitempty"`
+	}
+
+	// createdOutput - результат создания платежа в формате json
+	createdOutput struct {
+		PaymentId   int64  `json:"paymentId"`
+		RedirectUrl string `json:"redirectUrl,omitempty"`
+		Status      string `json:"status,omitempty"`
+		QRData      string `json:"qrData,omitempty"`
+	}
+
+	// shiftOutput - отчет по смене в формате json
+	shiftOutput struct {
+		Shift    string          `json:"shift"`
+		Payments []paymentOutput `json:"payments"`
+		Total    int64           `json:"total"`
+	}
+)
+
+func newPaymentOutput(info oacquiring.PaymentInfo) paymentOutput {
+	return paymentOutput{
+		Id:            info.Id,
+		Type:          paymentTypeName(info.Type),
+		Status:        info.Status.String(),
+		Sum:           info.Sum,
+		OrderNumber:   info.OrderNumber,
+		PursePublicId: info.PursePublicId,
+		CreatedDate:   timePtr(info.CreatedDate),
+		PaidDate:      timePtr(info.PaidDate),
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writePayment(w io.Writer, output string, info oacquiring.PaymentInfo) error {
+	if output == outputJSON {
+		return writeJSON(w, newPaymentOutput(info))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "ID\t%d\n", info.Id)
+	_, _ = fmt.Fprintf(tw, "Order number\t%s\n", info.OrderNumber)
+	_, _ = fmt.Fprintf(tw, "Type\t%s\n", paymentTypeName(info.Type))
+	_, _ = fmt.Fprintf(tw, "Status\t%s\n", info.Status)
+	_, _ = fmt.Fprintf(tw, "Sum\t%s\n", formatSum(info.Sum))
+	_, _ = fmt.Fprintf(tw, "Created\t%s\n", formatTime(info.CreatedDate))
+	_, _ = fmt.Fprintf(tw, "Paid\t%s\n", formatTime(info.PaidDate))
+	if info.PursePublicId != "" {
+		_, _ = fmt.Fprintf(tw, "Purse\t%s\n", info.PursePublicId)
+	}
+	return tw.Flush()
+}
+
+func writeCreated(w io.Writer, output string, created createdOutput) error {
+	if output == outputJSON {
+		return writeJSON(w, created)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Payment ID\t%d\n", created.PaymentId)
+	if created.RedirectUrl != "" {
+		_, _ = fmt.Fprintf(tw, "Redirect URL\t%s\n", created.RedirectUrl)
+	}
+	if created.Status != "" {
+		_, _ = fmt.Fprintf(tw, "Status\t%s\n", created.Status)
+	}
+	if created.QRData != "" {
+		_, _ = fmt.Fprintf(tw, "QR data\t%s\n", created.QRData)
+	}
+	return tw.Flush()
+}
+
+func writeShift(w io.Writer, output string, shift string, payments []oacquiring.PaymentInfo) error {
+	report := shiftOutput{Shift: shift, Payments: make([]paymentOutput, len(payments))}
+	for i, payment := range payments {
+		report.Payments[i] = newPaymentOutput(payment)
+		if payment.Status == oacquiring.PaymentStatusDone {
+			report.Total += signedSum(payment)
+		}
+	}
+
+	if output == outputJSON {
+		return writeJSON(w, report)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "ID\tORDER\tTYPE\tSTATUS\tSUM\tPAID\t")
+	for _, payment := range payments {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t\n", payment.Id, payment.OrderNumber,
+			paymentTypeName(payment.Type), payment.Status, formatSum(payment.Sum), formatTime(payment.PaidDate))
+	}
+	_, _ = fmt.Fprintf(tw, "\t\t\t\t%s\t\t\n", formatSum(report.Total))
+	return tw.Flush()
+}
+
+// signedSum возвращает сумму платежа со знаком: возвраты уменьшают итог смены
+func signedSum(payment oacquiring.PaymentInfo) int64 {
+	if payment.Type == oacquiring.PaymentItemTypeSellReverse || payment.Type == oacquiring.PaymentTypeBuy {
+		return -payment.Sum
+	}
+	return payment.Sum
+}
+
+func paymentTypeName(t oacquiring.PaymentType) string {
+	switch t {
+	case oacquiring.PaymentTypeSell:
+		return "SELL"
+	case oacquiring.PaymentTypeBuy:
+		return "BUY"
+	case oacquiring.PaymentItemTypeSellReverse:
+		return "SELL_REVERSE"
+	case oacquiring.PaymentItemTypeBuyReverse:
+		return "BUY_REVERSE"
+	default:
+		return "UNKNOWN(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// formatSum форматирует сумму в копейках как рубли, например 545 -> 5.45
+func formatSum(sum int64) string {
+	sign := ""
+	if sum < 0 {
+		sign, sum = "-", -sum
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, sum/100, sum%100)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
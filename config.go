@@ -0,0 +1,230 @@
+package oacquiring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Config - настройки Client и HTTPNotificationHandler. Может быть загружен из переменных окружения
+	// (LoadConfigFromEnv), yaml или json файла (LoadConfigFile). Пример yaml файла:
+	//
+	//	environment: production
+	//	regNum: OPL000011111
+	//	password: "1111"
+	//	timeout: 10s
+	//	retry:
+	//	  maxAttempts: 3
+	//	  initialBackoff: 200ms
+	//	  maxBackoff: 2s
+	//	notificationPublicKey: MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA...
+	Config struct {
+		Environment           string      `json:"environment" yaml:"environment"`                     // Окружение: EnvironmentSandbox или EnvironmentProduction
+		BaseUrl               string      `json:"baseUrl" yaml:"baseUrl"`                             // Базовый URL сервера Оплати. Если указан, Environment не используется
+		RegNum                string      `json:"regNum" yaml:"regNum"`                               // Регистрационный номер кассы
+		Password              string      `json:"password" yaml:"password"`                           // Пароль для интернет-кассы
+		CredentialsFile       string      `json:"credentialsFile" yaml:"credentialsFile"`             // json файл с Credentials, используется вместо RegNum и Password
+		Timeout               Duration    `json:"timeout" yaml:"timeout"`                             // Таймаут запроса к серверу Оплати. 0 - без таймаута
+		Retry                 RetryConfig `json:"retry" yaml:"retry"`                                 // Настройки повтора запросов
+		NotificationPublicKey string      `json:"notificationPublicKey" yaml:"notificationPublicKey"` // Публичный ключ для проверки подписи Server-Sign
+	}
+
+	// RetryConfig - настройки повтора запросов в Config, см. RetryPolicy
+	RetryConfig struct {
+		MaxAttempts    int      `json:"maxAttempts" yaml:"maxAttempts"`
+		InitialBackoff Duration `json:"initialBackoff" yaml:"initialBackoff"`
+		MaxBackoff     Duration `json:"maxBackoff" yaml:"maxBackoff"`
+	}
+
+	// Duration - time.Duration, который в json, yaml и переменных окружения записывается строкой вида "10s" или "1m30s"
+	Duration time.Duration
+)
+
+// credentialsFileCheckInterval - как часто проверять изменение Config.CredentialsFile
+const credentialsFileCheckInterval = 10 * time.Second
+
+// UnmarshalText реализует encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText реализует encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfigFromEnv загружает Config из переменных окружения с префиксом prefix (например, "OPLATI_"):
+//   - <prefix>ENVIRONMENT - Config.Environment
+//   - <prefix>BASE_URL - Config.BaseUrl
+//   - <prefix>REG_NUM - Config.RegNum
+//   - <prefix>PASSWORD - Config.Password
+//   - <prefix>CREDENTIALS_FILE - Config.CredentialsFile
+//   - <prefix>TIMEOUT - Config.Timeout
+//   - <prefix>RETRY_MAX_ATTEMPTS, <prefix>RETRY_INITIAL_BACKOFF, <prefix>RETRY_MAX_BACKOFF - Config.Retry
+//   - <prefix>NOTIFICATION_PUBLIC_KEY - Config.NotificationPublicKey
+//
+// Отсутствующие переменные оставляют соответствующие поля пустыми. Config не проверяется, используйте Config.Validate.
+func LoadConfigFromEnv(prefix string) (Config, error) {
+	cfg := Config{
+		Environment:           os.Getenv(prefix + "ENVIRONMENT"),
+		BaseUrl:               os.Getenv(prefix + "BASE_URL"),
+		RegNum:                os.Getenv(prefix + "REG_NUM"),
+		Password:              os.Getenv(prefix + "PASSWORD"),
+		CredentialsFile:       os.Getenv(prefix + "CREDENTIALS_FILE"),
+		NotificationPublicKey: os.Getenv(prefix + "NOTIFICATION_PUBLIC_KEY"),
+	}
+
+	durations := map[string]*Duration{
+		prefix + "TIMEOUT":               &cfg.Timeout,
+		prefix + "RETRY_INITIAL_BACKOFF": &cfg.Retry.InitialBackoff,
+		prefix + "RETRY_MAX_BACKOFF":     &cfg.Retry.MaxBackoff,
+	}
+	for name, d := range durations {
+		if value, ok := os.LookupEnv(name); ok {
+			err := d.UnmarshalText([]byte(value))
+			if err != nil {
+				return Config{}, fmt.Errorf("bad %s: %w", name, err)
+			}
+		}
+	}
+
+	if value, ok := os.LookupEnv(prefix + "RETRY_MAX_ATTEMPTS"); ok {
+		maxAttempts, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("bad %sRETRY_MAX_ATTEMPTS: %w", prefix, err)
+		}
+		cfg.Retry.MaxAttempts = maxAttempts
+	}
+
+	return cfg, nil
+}
+
+// LoadConfigFile загружает Config из файла. Формат определяется по расширению: .yaml, .yml или .json. Config не
+// проверяется, используйте Config.Validate.
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config reading failed: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseConfigYAML(data)
+	case ".json":
+		return ParseConfigJSON(data)
+	default:
+		return Config{}, fmt.Errorf("unsupported config file extension %q", filepath.Ext(path))
+	}
+}
+
+// ParseConfigJSON разбирает Config в формате json. Неизвестные поля считаются ошибкой.
+func ParseConfigJSON(data []byte) (Config, error) {
+	var cfg Config
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("config decoding failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// ParseConfigYAML разбирает Config в формате yaml. Неизвестные поля считаются ошибкой.
+func ParseConfigYAML(data []byte) (Config, error) {
+	var cfg Config
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("config decoding failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate проверяет настройки, необходимые для Client. Публичный ключ проверяется в
+// NewHTTPNotificationHandlerFromConfig.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.BaseUrl == "" {
+		if c.Environment == "" {
+			errs = append(errs, errors.New("baseUrl or environment should be specified"))
+		} else if _, err := EnvironmentBaseUrl(c.Environment); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.CredentialsFile == "" && (c.RegNum == "" || c.Password == "") {
+		errs = append(errs, errors.New("regNum and password or credentialsFile should be specified"))
+	}
+
+	if c.Timeout < 0 {
+		errs = append(errs, errors.New("timeout should not be negative"))
+	}
+	if c.Retry.MaxAttempts < 0 || c.Retry.InitialBackoff < 0 || c.Retry.MaxBackoff < 0 {
+		errs = append(errs, errors.New("retry settings should not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewClientFromConfig проверяет cfg и возвращает новый Client. opts применяются после настроек из cfg.
+func NewClientFromConfig(cfg Config, opts ...ClientOpt) (Client, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return Client{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	baseUrl := cfg.BaseUrl
+	if baseUrl == "" {
+		baseUrl, _ = EnvironmentBaseUrl(cfg.Environment)
+	}
+
+	cfgOpts := []ClientOpt{
+		WithCustomHTTPClient(http.Client{Timeout: time.Duration(cfg.Timeout)}),
+		WithRetry(RetryPolicy{
+			MaxAttempts:    cfg.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(cfg.Retry.InitialBackoff),
+			MaxBackoff:     time.Duration(cfg.Retry.MaxBackoff),
+		}),
+	}
+
+	if cfg.CredentialsFile != "" {
+		provider, err := NewFileCredentials(cfg.CredentialsFile, credentialsFileCheckInterval)
+		if err != nil {
+			return Client{}, err
+		}
+		cfgOpts = append(cfgOpts, WithCredentialsProvider(provider))
+	}
+
+	return NewClient(baseUrl, cfg.RegNum, cfg.Password, append(cfgOpts, opts...)...), nil
+}
+
+// NewHTTPNotificationHandlerFromConfig возвращает новый HTTPNotificationHandler с публичным ключом
+// Config.NotificationPublicKey.
+func NewHTTPNotificationHandlerFromConfig(cfg Config, paymentHandler PaymentNotificationHandler, opts ...NotificationHandlerOpt) (HTTPNotificationHandler, error) {
+	if cfg.NotificationPublicKey == "" {
+		return HTTPNotificationHandler{}, errors.New("invalid config: notificationPublicKey should be specified")
+	}
+
+	return NewHTTPNotificationHandler(cfg.NotificationPublicKey, paymentHandler, opts...)
+}
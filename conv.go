@@ -3,7 +3,6 @@ package oacquiring
 import (
 	"fmt"
 	"math"
-	"time"
 )
 
 func makePaymentItems(items []PaymentItem) ([]paymentRequestDetailsItem, float64) {
@@ -22,16 +21,16 @@ func makePaymentItems(items []PaymentItem) ([]paymentRequestDetailsItem, float64
 	return paymentItems, float64(sum) / 100
 }
 
-func (a *Client) makePaymentRequest(payment Payment) newPaymentRequest {
+func makePaymentRequest(regNum string, payment Payment) newPaymentRequest {
 	items, sum := makePaymentItems(payment.Items)
 
 	return newPaymentRequest{
 		Shift:       payment.Shift,
 		Sum:         sum,
 		OrderNumber: payment.OrderNumber,
-		RegNum:      a.cashboxRegNumber,
+		RegNum:      regNum,
 		Details: paymentRequestDetails{
-			RegNum:      a.cashboxRegNumber,
+			RegNum:      regNum,
 			Items:       items,
 			AmountTotal: sum,
 			FooterInfo:  payment.ReceiptFooterText,
@@ -42,16 +41,33 @@ func (a *Client) makePaymentRequest(payment Payment) newPaymentRequest {
 	}
 }
 
-func (a *Client) makeReversePaymentRequest(payment PaymentReversal) reversePaymentRequest {
+func makeReversePaymentRequest(regNum string, payment PaymentReversal) reversePaymentRequest {
 	items, sum := makePaymentItems(payment.Items)
 
 	return reversePaymentRequest{
 		Shift:       payment.Shift,
 		Sum:         sum,
 		OrderNumber: payment.OrderNumber,
-		RegNum:      a.cashboxRegNumber,
+		RegNum:      regNum,
 		Details: paymentRequestDetails{
-			RegNum:      a.cashboxRegNumber,
+			RegNum:      regNum,
+			Items:       items,
+			AmountTotal: sum,
+			FooterInfo:  payment.ReceiptFooterText,
+		},
+	}
+}
+
+func makePOSPaymentRequest(regNum string, payment POSPayment) newPOSPaymentRequest {
+	items, sum := makePaymentItems(payment.Items)
+
+	return newPOSPaymentRequest{
+		Shift:       payment.Shift,
+		Sum:         sum,
+		OrderNumber: payment.OrderNumber,
+		RegNum:      regNum,
+		Details: paymentRequestDetails{
+			RegNum:      regNum,
 			Items:       items,
 			AmountTotal: sum,
 			FooterInfo:  payment.ReceiptFooterText,
@@ -69,13 +85,13 @@ func makePaymentInfoFromRaw(rawPaymentInfo paymentInfoResponse) (PaymentInfo, er
 		PursePublicId: rawPaymentInfo.PursePublicId,
 	}
 
-	createdDate, err := time.Parse(time.RFC3339, rawPaymentInfo.CreatedDate)
+	createdDate, err := parseTimestamp(rawPaymentInfo.CreatedDate)
 	if err != nil {
 		return PaymentInfo{}, fmt.Errorf("bad payment createdDate: %w", err)
 	}
 	paymentInfo.CreatedDate = createdDate
 
-	paidDate, err := time.Parse(time.RFC3339, rawPaymentInfo.PaidDate)
+	paidDate, err := parseTimestamp(rawPaymentInfo.PaidDate)
 	if err != nil {
 		return PaymentInfo{}, fmt.Errorf("bad payment paidDate: %w", err)
 	}
@@ -83,3 +99,16 @@ func makePaymentInfoFromRaw(rawPaymentInfo paymentInfoResponse) (PaymentInfo, er
 
 	return paymentInfo, nil
 }
+
+func makeRawFromPaymentInfo(paymentInfo PaymentInfo) paymentInfoResponse {
+	return paymentInfoResponse{
+		PaymentId:     paymentInfo.Id,
+		PaymentType:   int(paymentInfo.Type),
+		Sum:           float64(paymentInfo.Sum) / 100,
+		Status:        int(paymentInfo.Status),
+		CreatedDate:   formatTimestamp(paymentInfo.CreatedDate),
+		PaidDate:      formatTimestamp(paymentInfo.PaidDate),
+		OrderNumber:   paymentInfo.OrderNumber,
+		PursePublicId: paymentInfo.PursePublicId,
+	}
+}
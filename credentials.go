@@ -0,0 +1,146 @@
+package oacquiring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// Credentials - данные для авторизации кассы на сервере Оплати
+	Credentials struct {
+		RegNum   string `json:"regNum"`   // Регистрационный номер кассы, например OPL000011111
+		Password string `json:"password"` // Пароль для интернет-кассы
+	}
+
+	// CredentialsProvider - источник данных для авторизации кассы. Client запрашивает Credentials перед каждым
+	// запросом, что позволяет менять пароль кассы без перезапуска приложения. Реализации должны быть безопасны для
+	// конкурентного использования. Для подключения используйте WithCredentialsProvider.
+	CredentialsProvider interface {
+		Credentials(ctx context.Context) (Credentials, error)
+	}
+
+	// CredentialsFunc - функция, реализующая CredentialsProvider. Например, для получения пароля из хранилища секретов
+	CredentialsFunc func(ctx context.Context) (Credentials, error)
+
+	// FileCredentialsProvider - CredentialsProvider, читающий Credentials из json файла вида
+	// {"regNum": "OPL000011111", "password": "1111"}. Файл перечитывается при изменении. Для инициализации используйте
+	// NewFileCredentials.
+	FileCredentialsProvider struct {
+		path          string
+		checkInterval time.Duration
+
+		mu          sync.Mutex
+		credentials Credentials
+		modTime     time.Time
+		size        int64
+		checkedAt   time.Time
+	}
+
+	staticCredentials Credentials
+
+	envCredentials struct {
+		regNumVar   string
+		passwordVar string
+	}
+)
+
+// Credentials реализует CredentialsProvider
+func (f CredentialsFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials возвращает CredentialsProvider с неизменными данными. Используется NewClient по умолчанию.
+func StaticCredentials(regNum, password string) CredentialsProvider {
+	return staticCredentials{RegNum: regNum, Password: password}
+}
+
+func (s staticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// EnvCredentials возвращает CredentialsProvider, читающий регистрационный номер и пароль кассы из переменных окружения
+// regNumVar и passwordVar при каждом запросе.
+func EnvCredentials(regNumVar, passwordVar string) CredentialsProvider {
+	return envCredentials{regNumVar: regNumVar, passwordVar: passwordVar}
+}
+
+func (e envCredentials) Credentials(context.Context) (Credentials, error) {
+	regNum, ok := os.LookupEnv(e.regNumVar)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s is not set", e.regNumVar)
+	}
+
+	password, ok := os.LookupEnv(e.passwordVar)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s is not set", e.passwordVar)
+	}
+
+	return Credentials{RegNum: regNum, Password: password}, nil
+}
+
+// NewFileCredentials возвращает новый FileCredentialsProvider.
+//   - path - Путь к json файлу с Credentials
+//   - checkInterval - Как часто проверять изменение файла. Если 0, файл проверяется при каждом запросе
+//
+// Файл читается сразу, ошибка чтения или разбора файла возвращается в качестве error.
+func NewFileCredentials(path string, checkInterval time.Duration) (*FileCredentialsProvider, error) {
+	p := &FileCredentialsProvider{
+		path:          path,
+		checkInterval: checkInterval,
+	}
+
+	_, err := p.Credentials(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Credentials реализует CredentialsProvider. Если файл изменился с момента последнего чтения, он перечитывается. Если
+// измененный файл не удалось прочитать, возвращается ошибка, чтобы не использовать устаревший пароль.
+func (p *FileCredentialsProvider) Credentials(context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if !p.checkedAt.IsZero() && now.Sub(p.checkedAt) < p.checkInterval {
+		return p.credentials, nil
+	}
+
+	stat, err := os.Stat(p.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials file stat failed: %w", err)
+	}
+
+	if !p.checkedAt.IsZero() && stat.ModTime().Equal(p.modTime) && stat.Size() == p.size {
+		p.checkedAt = now
+		return p.credentials, nil
+	}
+
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials file reading failed: %w", err)
+	}
+
+	var credentials Credentials
+	err = json.Unmarshal(raw, &credentials)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials file decoding failed: %w", err)
+	}
+	if credentials.RegNum == "" || credentials.Password == "" {
+		return Credentials{}, errors.New("credentials file should contain regNum and password")
+	}
+
+	p.credentials = credentials
+	p.modTime = stat.ModTime()
+	p.size = stat.Size()
+	p.checkedAt = now
+
+	return credentials, nil
+}
@@ -5,7 +5,14 @@
 //
 //	import oacquiring "github.com/oplati-by/go-acquiring"
 //	// ...
-//	oplatiClient := oacquiring.NewClient("https://oplati-cashboxapi.lwo-dev.by/ms-pay", "OPL000011111", "1111")
+//	oplatiClient := oacquiring.NewClient(oacquiring.BaseUrlSandbox, "OPL000011111", "1111")
+//
+// Для рабочего сервера используйте BaseUrlProduction. Настройки клиента можно загрузить из переменных окружения, yaml
+// или json файла с помощью LoadConfigFromEnv и LoadConfigFile:
+//
+//	cfg, err := oacquiring.LoadConfigFile("oplati.yaml")
+//	// ...
+//	oplatiClient, err := oacquiring.NewClientFromConfig(cfg)
 //
 // # Создание платежа:
 //
@@ -33,11 +40,33 @@
 //	result, err := oplatiClient.CreatePayment(context.Background(), paymentData)
 //	// ...
 //
+// # Создание платежа на кассе магазина
+//
+// CreatePOSPayment возвращает данные динамического QR кода для покупателя, WaitPayment ожидает окончательный статус:
+//
+//	posPayment, err := oplatiClient.CreatePOSPayment(context.Background(), oacquiring.POSPayment{
+//		Shift:       "14092001",
+//		OrderNumber: "AA-1112",
+//		Items:       items,
+//	})
+//	// ...
+//	paymentInfo, err := oplatiClient.WaitPayment(ctx, posPayment.PaymentId, 2*time.Second)
+//	// ...
+//
 // # Проверка статуса платежа
 //
 //	paymentInfo, err := oplatiClient.GetPaymentInfo(context.Background(), 123456)
 //	// ...
 //
+// # Отмена неоплаченного платежа
+//
+// Экспериментальный метод, запрос отмены отсутствует в опубликованном описании API:
+//
+//	paymentInfo, err := oplatiClient.CancelPayment(context.Background(), 123456)
+//	if errors.Is(err, oacquiring.ErrPaymentAlreadyFinished) {
+//	    // ...
+//	}
+//
 // # Отмена платежа (частичная или полная)
 //
 //	paymentData := oacquiring.PaymentReversal{
@@ -70,6 +99,12 @@
 //	    }
 //	}
 //
+// Retryable показывает, можно ли повторить запрос (HTTP 429 или 5xx):
+//
+//	if oplatiErr := (*oacquiring.ServerError)(nil); errors.As(err, &oplatiErr) && oplatiErr.Retryable() {
+//	    // Повторить запрос позже
+//	}
+//
 // # Получение уведомлений от сервера Оплати
 //
 // Реализация интерфейса PaymentNotificationHandler:
@@ -102,4 +137,9 @@
 //	 // Register HTTP handler, for example:
 //	 // http.Handle("/oplati/notification", &handler)
 //	 // http.ListenAndServe(":8080", nil)
+//
+// # Метрики
+//
+// Для сбора собственных метрик или логирования используйте ClientHooks и NotificationHooks. Готовый
+// prometheus.Collector находится в отдельном модуле github.com/oplati-by/go-acquiring/oprometheus.
 package oacquiring
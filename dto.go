@@ -67,3 +67,19 @@ type (
 		Details     paymentRequestDetails `json:"details"`
 	}
 )
+
+type (
+	newPOSPaymentRequest struct {
+		Shift       string                `json:"shift,omitempty"`
+		Sum         float64               `json:"sum"`
+		OrderNumber string                `json:"orderNumber"`
+		RegNum      string                `json:"regNum"`
+		Details     paymentRequestDetails `json:"details"`
+	}
+
+	newPOSPaymentResponse struct {
+		PaymentId int64  `json:"paymentId"`
+		Status    int    `json:"status"`
+		DynamicQR string `json:"dynamicQR"`
+	}
+)
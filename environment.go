@@ -0,0 +1,32 @@
+package oacquiring
+
+import "fmt"
+
+const (
+	// BaseUrlSandbox - базовый URL тестового сервера Оплати
+	BaseUrlSandbox = "https://oplati-cashboxapi.lwo-dev.by/ms-pay"
+	// BaseUrlProduction - базовый URL рабочего сервера Оплати
+	BaseUrlProduction = "https://cashboxapi.o-plati.by/ms-pay"
+)
+
+const (
+	// EnvironmentSandbox - тестовое окружение Оплати, BaseUrlSandbox
+	EnvironmentSandbox = "sandbox"
+	// EnvironmentProduction - рабочее окружение Оплати, BaseUrlProduction
+	EnvironmentProduction = "production"
+)
+
+var environments = map[string]string{
+	EnvironmentSandbox:    BaseUrlSandbox,
+	EnvironmentProduction: BaseUrlProduction,
+}
+
+// EnvironmentBaseUrl возвращает базовый URL сервера Оплати для окружения env (EnvironmentSandbox или
+// EnvironmentProduction).
+func EnvironmentBaseUrl(env string) (string, error) {
+	baseUrl, ok := environments[env]
+	if !ok {
+		return "", fmt.Errorf("unknown environment %q", env)
+	}
+	return baseUrl, nil
+}
@@ -0,0 +1,45 @@
+package oacquiring
+
+import "net/http"
+
+// ErrUnknownServerError - ошибка Оплати с внутренним кодом, отсутствующим в каталоге errorCodes. Используется как
+// ServerError.Code для неизвестных кодов, поэтому errors.Is(err, ErrUnknownServerError) == true для любой ошибки
+// сервера с кодом вне каталога.
+var ErrUnknownServerError = &ErrorCode{Description: "unknown server error"}
+
+// errorCodes - каталог известных кодов ошибок Оплати. Коды добавляются только после сверки с документацией API: если
+// код в каталоге не совпадет с возвращаемым сервером, errors.Is с соответствующей ErrorCode никогда не сработает.
+var errorCodes = map[string]*ErrorCode{}
+
+type (
+	// ErrorCode - код ошибки Оплати (ServerError.InternalCode). Значения ErrorCode используются как сигнальные ошибки:
+	//
+	//	if errors.Is(err, oacquiring.ErrUnknownServerError) {
+	//	    log.Printf("unexpected error code %s", oplatiErr.InternalCode)
+	//	}
+	ErrorCode struct {
+		Code        string // Внутренний код ошибки
+		Description string // Описание ошибки
+	}
+)
+
+// LookupErrorCode возвращает ErrorCode по внутреннему коду ошибки Оплати. Для неизвестных кодов возвращает
+// ErrUnknownServerError.
+func LookupErrorCode(internalCode string) *ErrorCode {
+	if e, ok := errorCodes[internalCode]; ok {
+		return e
+	}
+	return ErrUnknownServerError
+}
+
+func (e *ErrorCode) Error() string {
+	if e.Code == "" {
+		return "OPLATI error: " + e.Description
+	}
+	return "OPLATI error " + e.Code + ": " + e.Description
+}
+
+// isRetryableStatus возвращает true для HTTP статусов, при которых запрос можно повторить
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
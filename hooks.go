@@ -0,0 +1,113 @@
+package oacquiring
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type (
+	// ClientHooks - набор функций, которые Client вызывает при выполнении операций. Используется для сбора метрик,
+	// логирования и т.п. Любое из полей может быть nil. Функции вызываются синхронно в горутине, выполняющей
+	// операцию, поэтому не должны блокироваться надолго. Для подключения используйте WithClientHooks.
+	ClientHooks struct {
+		// RequestDone вызывается после каждого запроса к серверу Оплати. duration - время от отправки запроса до
+		// получения ответа, без ожидания ограничителя частоты запросов. err - ошибка запроса либо nil. Для запросов,
+		// отклоненных автоматическим выключателем, не вызывается.
+		RequestDone func(ctx context.Context, op Operation, duration time.Duration, err error)
+
+		// RequestRejected вызывается, если запрос не был отправлен, так как автоматический выключатель разомкнут
+		// (err содержит ErrCircuitOpen).
+		RequestRejected func(ctx context.Context, op Operation, err error)
+
+		// RateLimitWaited вызывается после ожидания ограничителя частоты запросов (см. WithRateLimit). err - ошибка
+		// ожидания (например, отмена ctx) либо nil.
+		RateLimitWaited func(ctx context.Context, op Operation, wait time.Duration, err error)
+
+		// PaymentCreated вызывается после успешного создания платежа методами Client.CreatePayment и
+		// Client.CreatePOSPayment. Для платежей на кассе магазина result.RedirectUrl пустой.
+		PaymentCreated func(ctx context.Context, payment Payment, result SuccessfulPayment)
+
+		// PaymentReversed вызывается после успешного возврата платежа методом Client.ReversePayment.
+		PaymentReversed func(ctx context.Context, paymentId int64, reversal PaymentReversal, info PaymentInfo)
+
+		// PaymentInfoReceived вызывается для каждого PaymentInfo, полученного методами Client.GetPaymentInfo,
+		// Client.GetPaymentsOnShift и Client.CancelPayment.
+		PaymentInfoReceived func(ctx context.Context, op Operation, info PaymentInfo)
+	}
+
+	// NotificationHooks - набор функций, которые HTTPNotificationHandler вызывает при обработке уведомлений. Любое из
+	// полей может быть nil. Для подключения используйте WithNotificationHooks.
+	NotificationHooks struct {
+		// SignatureFailed вызывается, если подпись Server-Sign отсутствует или неверна.
+		SignatureFailed func(r *http.Request, err error)
+
+		// PaymentReceived вызывается после обработки корректного уведомления. err - результат
+		// PaymentNotificationHandler.HandlePayment.
+		PaymentReceived func(ctx context.Context, info PaymentInfo, err error)
+	}
+)
+
+func (a *Client) requestDone(ctx context.Context, op Operation, duration time.Duration, err error) {
+	for _, h := range a.hooks {
+		if h.RequestDone != nil {
+			h.RequestDone(ctx, op, duration, err)
+		}
+	}
+}
+
+func (a *Client) requestRejected(ctx context.Context, op Operation, err error) {
+	for _, h := range a.hooks {
+		if h.RequestRejected != nil {
+			h.RequestRejected(ctx, op, err)
+		}
+	}
+}
+
+func (a *Client) rateLimitWaited(ctx context.Context, op Operation, wait time.Duration, err error) {
+	for _, h := range a.hooks {
+		if h.RateLimitWaited != nil {
+			h.RateLimitWaited(ctx, op, wait, err)
+		}
+	}
+}
+
+func (a *Client) paymentCreated(ctx context.Context, payment Payment, result SuccessfulPayment) {
+	for _, h := range a.hooks {
+		if h.PaymentCreated != nil {
+			h.PaymentCreated(ctx, payment, result)
+		}
+	}
+}
+
+func (a *Client) paymentReversed(ctx context.Context, paymentId int64, reversal PaymentReversal, info PaymentInfo) {
+	for _, h := range a.hooks {
+		if h.PaymentReversed != nil {
+			h.PaymentReversed(ctx, paymentId, reversal, info)
+		}
+	}
+}
+
+func (a *Client) paymentInfoReceived(ctx context.Context, op Operation, info PaymentInfo) {
+	for _, h := range a.hooks {
+		if h.PaymentInfoReceived != nil {
+			h.PaymentInfoReceived(ctx, op, info)
+		}
+	}
+}
+
+func (nh *HTTPNotificationHandler) signatureFailed(r *http.Request, err error) {
+	for _, h := range nh.hooks {
+		if h.SignatureFailed != nil {
+			h.SignatureFailed(r, err)
+		}
+	}
+}
+
+func (nh *HTTPNotificationHandler) paymentReceived(ctx context.Context, info PaymentInfo, err error) {
+	for _, h := range nh.hooks {
+		if h.PaymentReceived != nil {
+			h.PaymentReceived(ctx, info, err)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package oacquiring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// EventSourceCreate - платеж создан методом Client.CreatePayment или Client.CreatePOSPayment
+	EventSourceCreate PaymentEventSource = iota + 1
+	// EventSourceNotification - уведомление от сервера Оплати
+	EventSourceNotification
+	// EventSourcePoll - результат Client.GetPaymentInfo, Client.GetPaymentsOnShift или Client.CancelPayment
+	EventSourcePoll
+	// EventSourceReversal - результат Client.ReversePayment
+	EventSourceReversal
+)
+
+const (
+	// defaultFinalStateTTL - время хранения окончательных состояний платежей по умолчанию
+	defaultFinalStateTTL = 24 * time.Hour
+	// defaultPendingStateTTL - время хранения неокончательных состояний платежей по умолчанию
+	defaultPendingStateTTL = 24 * time.Hour
+)
+
+// ErrIllegalTransition - событие не может быть применено к текущему состоянию платежа. Как правило, это устаревшее
+// событие (например, опрос со статусом PaymentStatusInProgress, полученный после уведомления об оплате)
+var ErrIllegalTransition = errors.New("illegal payment transition")
+
+type (
+	// PaymentEventSource - источник события платежа. Варианты: EventSourceCreate, EventSourceNotification,
+	// EventSourcePoll, EventSourceReversal
+	PaymentEventSource int
+
+	// PaymentEvent - событие, изменяющее состояние платежа
+	PaymentEvent struct {
+		Source PaymentEventSource // Источник события
+		Info   PaymentInfo        // Данные платежа. Для EventSourceReversal - идентификатор возвращаемого платежа в Id
+		// ReversalSum - сумма возврата в копейках, только для EventSourceReversal
+		ReversalSum int64
+		// ReversalType - тип операции возврата (PaymentItemTypeSellReverse или PaymentItemTypeBuyReverse), только для
+		// EventSourceReversal. Если 0, тип не проверяется
+		ReversalType PaymentType
+	}
+
+	// PaymentState - известное состояние платежа
+	PaymentState struct {
+		Id          int64         // Идентификатор платежа в Оплати
+		Type        PaymentType   // Тип платежа, если известен
+		Status      PaymentStatus // Статус платежа
+		Sum         int64         // Сумма платежа в копейках
+		ReversedSum int64         // Сумма выполненных возвратов в копейках
+		Info        PaymentInfo   // Последние полученные данные платежа
+		UpdatedAt   time.Time     // Время последнего изменения состояния
+	}
+
+	// PaymentTransition - изменение состояния платежа
+	PaymentTransition struct {
+		From  PaymentState // Предыдущее состояние. Нулевое значение, если платеж ранее не был известен
+		To    PaymentState // Новое состояние
+		Event PaymentEvent // Событие, вызвавшее изменение
+	}
+
+	// PaymentLifecycle - конечный автомат состояний платежей. Принимает события из уведомлений, опросов статуса и
+	// возвратов, отклоняет недопустимые переходы (см. CanTransition) и вызывает хуки при каждом изменении состояния.
+	// Позволяет не перезаписывать более новое состояние платежа устаревшим, если уведомления и опросы приходят не по
+	// порядку. Состояния хранятся в памяти и удаляются через время, заданное WithFinalStateTTL для окончательных
+	// состояний и WithPendingStateTTL для остальных (например, брошенных корзин). Безопасен для конкурентного
+	// использования. Для инициализации используйте NewPaymentLifecycle.
+	PaymentLifecycle struct {
+		onTransition    []func(PaymentTransition)
+		onRejected      []func(PaymentEvent, error)
+		finalStateTTL   time.Duration
+		pendingStateTTL time.Duration
+
+		mu          sync.Mutex
+		states      map[int64]PaymentState
+		lastEvicted time.Time
+	}
+
+	// PaymentLifecycleOpt - дополнительные параметры PaymentLifecycle
+	PaymentLifecycleOpt func(*PaymentLifecycle)
+
+	lifecycleNotificationHandler struct {
+		lifecycle *PaymentLifecycle
+		next      PaymentNotificationHandler
+	}
+)
+
+func (s PaymentEventSource) String() string {
+	switch s {
+	case EventSourceCreate:
+		return "create"
+	case EventSourceNotification:
+		return "notification"
+	case EventSourcePoll:
+		return "poll"
+	case EventSourceReversal:
+		return "reversal"
+	default:
+		return fmt.Sprintf("PaymentEventSource(%d)", int(s))
+	}
+}
+
+// IsFinal возвращает true для окончательных статусов платежа, т.е. всех, кроме PaymentStatusInProgress
+func (s PaymentStatus) IsFinal() bool {
+	return s != PaymentStatusInProgress
+}
+
+// CanTransition возвращает true, если платеж может перейти из статуса from в статус to:
+//   - PaymentStatusInProgress может перейти в любой статус
+//   - окончательный статус может перейти только в тот же статус (повторное событие)
+func CanTransition(from, to PaymentStatus) bool {
+	return !from.IsFinal() || from == to
+}
+
+// reversalTypeFor возвращает тип операции возврата для типа платежа t
+func reversalTypeFor(t PaymentType) PaymentType {
+	switch t {
+	case PaymentTypeSell:
+		return PaymentItemTypeSellReverse
+	case PaymentTypeBuy:
+		return PaymentItemTypeBuyReverse
+	default:
+		return 0
+	}
+}
+
+// WithTransitionHook - добавляет функцию, вызываемую после каждого изменения состояния платежа. Функция вызывается
+// синхронно, вне блокировки PaymentLifecycle
+func WithTransitionHook(hook func(PaymentTransition)) PaymentLifecycleOpt {
+	return func(l *PaymentLifecycle) {
+		l.onTransition = append(l.onTransition, hook)
+	}
+}
+
+// WithRejectedHook - добавляет функцию, вызываемую для каждого отклоненного события
+func WithRejectedHook(hook func(PaymentEvent, error)) PaymentLifecycleOpt {
+	return func(l *PaymentLifecycle) {
+		l.onRejected = append(l.onRejected, hook)
+	}
+}
+
+// WithFinalStateTTL - время, в течение которого хранится окончательное состояние платежа после последнего
+// изменения. Пока состояние хранится, устаревшие события отклоняются, а возвраты проверяются по сумме платежа; после
+// удаления возврат платежа отклоняется как возврат неизвестного платежа. По умолчанию 24 часа, 0 или меньше - без
+// удаления
+func WithFinalStateTTL(ttl time.Duration) PaymentLifecycleOpt {
+	return func(l *PaymentLifecycle) {
+		l.finalStateTTL = ttl
+	}
+}
+
+// WithPendingStateTTL - время, в течение которого хранится неокончательное состояние платежа (например,
+// PaymentStatusInProgress для брошенной корзины) после последнего изменения. Событие для удаленного платежа
+// применяется как событие неизвестного платежа. По умолчанию 24 часа, 0 или меньше - без удаления (в этом случае
+// удаляйте состояния с помощью Forget)
+func WithPendingStateTTL(ttl time.Duration) PaymentLifecycleOpt {
+	return func(l *PaymentLifecycle) {
+		l.pendingStateTTL = ttl
+	}
+}
+
+// NewPaymentLifecycle возвращает новый PaymentLifecycle без известных платежей
+func NewPaymentLifecycle(opts ...PaymentLifecycleOpt) *PaymentLifecycle {
+	l := &PaymentLifecycle{
+		states:          make(map[int64]PaymentState),
+		finalStateTTL:   defaultFinalStateTTL,
+		pendingStateTTL: defaultPendingStateTTL,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Load добавляет или заменяет состояние платежа без проверки переходов и вызова хуков. Используется для
+// восстановления состояний, например из БД. Если UpdatedAt не заполнен, используется текущее время.
+func (l *PaymentLifecycle) Load(state PaymentState) {
+	if state.UpdatedAt.IsZero() {
+		state.UpdatedAt = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.states[state.Id] = state
+}
+
+// State возвращает известное состояние платежа
+func (l *PaymentLifecycle) State(paymentId int64) (PaymentState, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state, ok := l.states[paymentId]
+	return state, ok
+}
+
+// Forget удаляет состояние платежа, например после его обработки
+func (l *PaymentLifecycle) Forget(paymentId int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.states, paymentId)
+}
+
+// Apply применяет событие к состоянию платежа. Если событие не изменяет состояние (повторное событие), возвращается
+// changed == false. Если переход недопустим, возвращается ошибка, для которой errors.Is(err, ErrIllegalTransition) ==
+// true, а состояние не изменяется.
+func (l *PaymentLifecycle) Apply(event PaymentEvent) (transition PaymentTransition, changed bool, err error) {
+	l.mu.Lock()
+	l.evictLocked()
+	from, known := l.states[event.Info.Id]
+	to, err := nextState(from, known, event)
+	changed = err == nil && (!known || to.Status != from.Status || to.ReversedSum != from.ReversedSum)
+	if changed {
+		to.UpdatedAt = time.Now()
+		l.states[to.Id] = to
+	} else if err == nil {
+		// Обновление данных платежа без изменения состояния
+		to.UpdatedAt = from.UpdatedAt
+		l.states[to.Id] = to
+	}
+	l.mu.Unlock()
+
+	if err != nil {
+		for _, hook := range l.onRejected {
+			hook(event, err)
+		}
+		return PaymentTransition{}, false, err
+	}
+
+	transition = PaymentTransition{From: from, To: to, Event: event}
+	if changed {
+		for _, hook := range l.onTransition {
+			hook(transition)
+		}
+	}
+
+	return transition, changed, nil
+}
+
+// wouldChange возвращает true, если Apply(event) изменит состояние платежа. Состояние не изменяется, хуки не
+// вызываются.
+func (l *PaymentLifecycle) wouldChange(event PaymentEvent) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	from, known := l.states[event.Info.Id]
+	to, err := nextState(from, known, event)
+	return err == nil && (!known || to.Status != from.Status || to.ReversedSum != from.ReversedSum)
+}
+
+// evictLocked удаляет состояния, не изменявшиеся дольше finalStateTTL (окончательные) или pendingStateTTL
+// (остальные). Проверка выполняется не чаще, чем раз в половину меньшего из них.
+func (l *PaymentLifecycle) evictLocked() {
+	interval := l.finalStateTTL
+	if interval <= 0 || l.pendingStateTTL > 0 && l.pendingStateTTL < interval {
+		interval = l.pendingStateTTL
+	}
+	if interval <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastEvicted) < interval/2 {
+		return
+	}
+	l.lastEvicted = now
+
+	for id, state := range l.states {
+		ttl := l.pendingStateTTL
+		if state.Status.IsFinal() {
+			ttl = l.finalStateTTL
+		}
+		if ttl > 0 && now.Sub(state.UpdatedAt) > ttl {
+			delete(l.states, id)
+		}
+	}
+}
+
+// nextState вычисляет состояние платежа после события
+func nextState(from PaymentState, known bool, event PaymentEvent) (PaymentState, error) {
+	if event.Source == EventSourceReversal {
+		return nextReversalState(from, known, event)
+	}
+
+	info := event.Info
+	if known && !CanTransition(from.Status, info.Status) {
+		return PaymentState{}, fmt.Errorf("%w: payment %d %s -> %s by %s", ErrIllegalTransition, info.Id, from.Status, info.Status, event.Source)
+	}
+
+	to := from
+	to.Id = info.Id
+	to.Status = info.Status
+	to.Info = info
+	if info.Type != 0 {
+		to.Type = info.Type
+	}
+	if info.Sum != 0 {
+		to.Sum = info.Sum
+	}
+
+	return to, nil
+}
+
+// nextReversalState вычисляет состояние платежа после возврата: возврат возможен только для оплаченного платежа,
+// сумма возвратов не может превышать сумму платежа, тип операции возврата должен соответствовать типу платежа
+func nextReversalState(from PaymentState, known bool, event PaymentEvent) (PaymentState, error) {
+	id := event.Info.Id
+	if !known {
+		return PaymentState{}, fmt.Errorf("%w: reversal of unknown payment %d", ErrIllegalTransition, id)
+	}
+	if from.Status != PaymentStatusDone {
+		return PaymentState{}, fmt.Errorf("%w: reversal of payment %d in status %s", ErrIllegalTransition, id, from.Status)
+	}
+	if event.ReversalSum <= 0 {
+		return PaymentState{}, fmt.Errorf("%w: reversal of payment %d with non-positive sum", ErrIllegalTransition, id)
+	}
+	if from.Sum != 0 && from.ReversedSum+event.ReversalSum > from.Sum {
+		return PaymentState{}, fmt.Errorf("%w: reversals of payment %d exceed payment sum", ErrIllegalTransition, id)
+	}
+	if expected := reversalTypeFor(from.Type); event.ReversalType != 0 && expected != 0 && event.ReversalType != expected {
+		return PaymentState{}, fmt.Errorf("%w: reversal type %d does not match payment %d type %d", ErrIllegalTransition, event.ReversalType, id, from.Type)
+	}
+
+	to := from
+	to.ReversedSum += event.ReversalSum
+	return to, nil
+}
+
+// ClientHooks возвращает ClientHooks, передающие в PaymentLifecycle события созданных платежей, опросов статуса и
+// возвратов. Отклоненные события передаются в хуки WithRejectedHook.
+func (l *PaymentLifecycle) ClientHooks() ClientHooks {
+	return ClientHooks{
+		PaymentCreated: func(_ context.Context, payment Payment, result SuccessfulPayment) {
+			var sum int64
+			for _, item := range payment.Items {
+				sum += item.Cost
+			}
+			_, _, _ = l.Apply(PaymentEvent{
+				Source: EventSourceCreate,
+				Info: PaymentInfo{
+					Id:          result.PaymentId,
+					Sum:         sum,
+					Status:      PaymentStatusInProgress,
+					OrderNumber: payment.OrderNumber,
+				},
+			})
+		},
+		PaymentInfoReceived: func(_ context.Context, _ Operation, info PaymentInfo) {
+			_, _, _ = l.Apply(PaymentEvent{Source: EventSourcePoll, Info: info})
+		},
+		PaymentReversed: func(_ context.Context, paymentId int64, reversal PaymentReversal, info PaymentInfo) {
+			var sum int64
+			for _, item := range reversal.Items {
+				sum += item.Cost
+			}
+			event := PaymentEvent{
+				Source:      EventSourceReversal,
+				Info:        PaymentInfo{Id: paymentId, OrderNumber: reversal.OrderNumber},
+				ReversalSum: sum,
+			}
+			if info.Type == PaymentItemTypeSellReverse || info.Type == PaymentItemTypeBuyReverse {
+				event.ReversalType = info.Type
+			}
+			_, _, _ = l.Apply(event)
+		},
+	}
+}
+
+// NotificationHandler возвращает PaymentNotificationHandler, который вызывает next только для уведомлений, изменяющих
+// состояние платежа, и применяет уведомление к PaymentLifecycle после успешной обработки next. Если next вернул
+// ошибку, состояние не изменяется и хуки не вызываются, поэтому повторное уведомление будет обработано. Повторные и
+// устаревшие уведомления подтверждаются серверу Оплати без вызова next. Одновременные одинаковые уведомления могут
+// быть переданы в next несколько раз.
+func (l *PaymentLifecycle) NotificationHandler(next PaymentNotificationHandler) PaymentNotificationHandler {
+	return &lifecycleNotificationHandler{lifecycle: l, next: next}
+}
+
+func (h *lifecycleNotificationHandler) HandlePayment(payment PaymentInfo) error {
+	return h.ReceiveNotification(context.Background(), Notification{Payment: payment})
+}
+
+// ReceiveNotification реализует NotificationReceiver, чтобы next получил полное уведомление
+func (h *lifecycleNotificationHandler) ReceiveNotification(ctx context.Context, notification Notification) error {
+	event := PaymentEvent{Source: EventSourceNotification, Info: notification.Payment}
+	if !h.lifecycle.wouldChange(event) {
+		// Повторное или устаревшее уведомление: Apply обновит данные платежа либо вызовет хуки WithRejectedHook
+		_, _, _ = h.lifecycle.Apply(event)
+		return nil
+	}
+
+	err := DeliverNotification(ctx, h.next, notification)
+	if err != nil {
+		return err
+	}
+
+	// Состояние могло измениться во время обработки (например, опросом с тем же статусом), поэтому переход
+	// проверяется повторно
+	_, _, _ = h.lifecycle.Apply(event)
+	return nil
+}
@@ -0,0 +1,165 @@
+package oacquiring
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+// RedactedValue - значение, которым заменяются скрытые заголовки
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders - заголовки, скрываемые по умолчанию при выводе запросов и ответов
+var DefaultRedactedHeaders = []string{"Password", "Authorization", "Cookie", "Set-Cookie"}
+
+type (
+	// Doer - интерфейс для выполнения HTTP запросов. Реализуется *http.Client
+	Doer interface {
+		Do(r *http.Request) (*http.Response, error)
+	}
+
+	// DoerFunc - функция, реализующая Doer
+	DoerFunc func(r *http.Request) (*http.Response, error)
+
+	// Middleware - промежуточный обработчик запросов Client к серверу Оплати. Получает следующий Doer в цепочке и
+	// возвращает Doer, который должен его вызвать. Название выполняемой операции можно получить из контекста запроса
+	// с помощью OperationFromContext. Для подключения используйте WithMiddleware.
+	Middleware func(next Doer) Doer
+
+	operationCtxKey struct{}
+)
+
+// Do реализует Doer
+func (f DoerFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// OperationFromContext возвращает название операции Client, выполняющей запрос с контекстом ctx
+func OperationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(operationCtxKey{}).(Operation)
+	return op, ok
+}
+
+func contextWithOperation(ctx context.Context, op Operation) context.Context {
+	return context.WithValue(ctx, operationCtxKey{}, op)
+}
+
+// doer возвращает Doer с цепочкой Middleware вокруг http.Client
+func (a *Client) doer() Doer {
+	var d Doer = &a.httpClient
+	for i := len(a.middlewares) - 1; i >= 0; i-- {
+		d = a.middlewares[i](d)
+	}
+	return d
+}
+
+// UserAgentMiddleware возвращает Middleware, устанавливающий заголовок User-Agent
+func UserAgentMiddleware(userAgent string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(r *http.Request) (*http.Response, error) {
+			r.Header.Set("User-Agent", userAgent)
+			return next.Do(r)
+		})
+	}
+}
+
+// RequestIdMiddleware возвращает Middleware, устанавливающий заголовок header (например, X-Request-Id) в значение,
+// полученное из generate. Если generate равен nil, используется случайный идентификатор из 16 байт в hex. Если
+// заголовок уже установлен предыдущим Middleware, он не изменяется.
+func RequestIdMiddleware(header string, generate func(ctx context.Context) string) Middleware {
+	if generate == nil {
+		generate = randomRequestId
+	}
+
+	return func(next Doer) Doer {
+		return DoerFunc(func(r *http.Request) (*http.Response, error) {
+			if r.Header.Get(header) == "" {
+				r.Header.Set(header, generate(r.Context()))
+			}
+			return next.Do(r)
+		})
+	}
+}
+
+func randomRequestId(context.Context) string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// RedactHeaders возвращает копию header, в которой значения заголовков names заменены на RedactedValue. Если names не
+// указаны, используются DefaultRedactedHeaders.
+func RedactHeaders(header http.Header, names ...string) http.Header {
+	if len(names) == 0 {
+		names = DefaultRedactedHeaders
+	}
+
+	redacted := header.Clone()
+	for _, name := range names {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, RedactedValue)
+		}
+	}
+
+	return redacted
+}
+
+// DumpMiddleware возвращает Middleware, записывающий в w каждый запрос и ответ целиком (с заголовками и телом) в
+// формате httputil.DumpRequestOut и httputil.DumpResponse. Значения заголовков redact (или DefaultRedactedHeaders,
+// если redact не указаны) скрываются. Предназначен для отладки.
+func DumpMiddleware(w io.Writer, redact ...string) Middleware {
+	var mu sync.Mutex
+
+	return func(next Doer) Doer {
+		return DoerFunc(func(r *http.Request) (*http.Response, error) {
+			op, _ := OperationFromContext(r.Context())
+
+			clone := r.Clone(r.Context())
+			clone.Header = RedactHeaders(r.Header, redact...)
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("request body copying failed: %w", err)
+				}
+				clone.Body = body
+			}
+
+			dump, err := httputil.DumpRequestOut(clone, r.GetBody != nil)
+			if err != nil {
+				return nil, fmt.Errorf("request dumping failed: %w", err)
+			}
+
+			mu.Lock()
+			_, _ = fmt.Fprintf(w, "--> %s\n%s\n", op, dump)
+			mu.Unlock()
+
+			resp, err := next.Do(r)
+			if err != nil {
+				mu.Lock()
+				_, _ = fmt.Fprintf(w, "<-- %s error: %s\n\n", op, err)
+				mu.Unlock()
+				return nil, err
+			}
+
+			header := resp.Header
+			resp.Header = RedactHeaders(header, redact...)
+			dump, err = httputil.DumpResponse(resp, true)
+			resp.Header = header
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, fmt.Errorf("response dumping failed: %w", err)
+			}
+
+			mu.Lock()
+			_, _ = fmt.Fprintf(w, "<-- %s\n%s\n\n", op, dump)
+			mu.Unlock()
+
+			return resp, nil
+		})
+	}
+}
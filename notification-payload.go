@@ -0,0 +1,101 @@
+package oacquiring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// knownNotificationFields - поля тела уведомления, разбираемые в PaymentInfo
+var knownNotificationFields = []string{
+	"paymentId", "paymentType", "sum", "status", "createdDate", "paidDate", "orderNumber", "pursePublicId",
+}
+
+type (
+	// Notification - уведомление от сервера Оплати с проверенной подписью
+	Notification struct {
+		Payment PaymentInfo    // Данные платежа
+		Body    []byte         // Исходное тело уведомления, подпись которого была проверена
+		Header  http.Header    // Заголовки запроса
+		Extra   map[string]any // Поля тела, не входящие в PaymentInfo. Числа представлены json.Number
+	}
+
+	// NotificationReceiver - дополнительный интерфейс PaymentNotificationHandler. Если обработчик, переданный в
+	// NewHTTPNotificationHandler, реализует NotificationReceiver, вместо HandlePayment вызывается ReceiveNotification
+	// с полным уведомлением. Ошибка обрабатывается так же, как ошибка HandlePayment.
+	NotificationReceiver interface {
+		ReceiveNotification(ctx context.Context, notification Notification) error
+	}
+
+	// NotificationReceiverFunc - функция, реализующая NotificationReceiver и PaymentNotificationHandler. Может быть
+	// передана в NewHTTPNotificationHandler:
+	//
+	//	handler, err := oacquiring.NewHTTPNotificationHandler(key, oacquiring.NotificationReceiverFunc(
+	//	    func(ctx context.Context, n oacquiring.Notification) error {
+	//	        // n.Payment, n.Body, n.Extra["newField"]
+	//	        return nil
+	//	    }))
+	NotificationReceiverFunc func(ctx context.Context, notification Notification) error
+)
+
+// DecodeNotification разбирает тело уведомления от сервера Оплати в Notification. Подпись не проверяется, используйте
+// VerifyNotification.
+func DecodeNotification(body []byte, header http.Header) (Notification, error) {
+	payment, err := ParseNotification(body)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err = decoder.Decode(&fields)
+	if err != nil {
+		return Notification{}, fmt.Errorf("notification decoding failed: %w", err)
+	}
+
+	for _, name := range knownNotificationFields {
+		delete(fields, name)
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	return Notification{
+		Payment: payment,
+		Body:    body,
+		Header:  header,
+		Extra:   fields,
+	}, nil
+}
+
+// Decode разбирает тело уведомления в v. Позволяет получить поля, добавленные в API Оплати, в собственную структуру.
+func (n Notification) Decode(v any) error {
+	err := json.Unmarshal(n.Body, v)
+	if err != nil {
+		return fmt.Errorf("notification decoding failed: %w", err)
+	}
+	return nil
+}
+
+// ReceiveNotification реализует NotificationReceiver
+func (f NotificationReceiverFunc) ReceiveNotification(ctx context.Context, notification Notification) error {
+	return f(ctx, notification)
+}
+
+// HandlePayment реализует PaymentNotificationHandler. Вызывается, только если NotificationReceiverFunc используется
+// как PaymentNotificationHandler вне HTTPNotificationHandler; Notification содержит только Payment.
+func (f NotificationReceiverFunc) HandlePayment(payment PaymentInfo) error {
+	return f(context.Background(), Notification{Payment: payment})
+}
+
+// DeliverNotification передает уведомление обработчику handler: через ReceiveNotification, если handler реализует
+// NotificationReceiver, иначе через HandlePayment. Используйте в обработчиках-обертках, чтобы не терять Notification.
+func DeliverNotification(ctx context.Context, handler PaymentNotificationHandler, notification Notification) error {
+	if receiver, ok := handler.(NotificationReceiver); ok {
+		return receiver.ReceiveNotification(ctx, notification)
+	}
+	return handler.HandlePayment(notification.Payment)
+}
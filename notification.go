@@ -1,14 +1,8 @@
 package oacquiring
 
 import (
-	"crypto"
 	"crypto/rsa"
-	"crypto/sha256"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 )
@@ -23,86 +17,105 @@ type (
 		HandlePayment(PaymentInfo) error
 	}
 
+	// PaymentNotificationHandlerFunc - функция, реализующая PaymentNotificationHandler
+	PaymentNotificationHandlerFunc func(PaymentInfo) error
+
 	// HTTPNotificationHandler - обработчик HTTP уведомления от сервера Оплати, реализует интерфейс
 	// http.Handler. Осуществляет:
 	//  1. Проверку подписи Server-Sign. В случае, если запрос подписан неверно, клиент получит ответ "401 Unauthorized"
 	//  2. Преобразования тела запроса в PaymentInfo. В случае, если получен некорректный json, клиент получит
 	//  ответ "400 Bad Request"
-	//  3. Выполнение логики PaymentNotificationHandler.HandlePayment с корректным PaymentInfo
+	//  3. Выполнение логики PaymentNotificationHandler.HandlePayment с корректным PaymentInfo (или
+	//  NotificationReceiver.ReceiveNotification с Notification, если обработчик его реализует)
 	//  4. Отправка ответа клиенту в зависимости от успеха выполнения шага 3
 	// Для инициализации используйте NewHTTPNotificationHandler.
 	HTTPNotificationHandler struct {
 		publicKey *rsa.PublicKey
 		handler   PaymentNotificationHandler
+
+		hooks []NotificationHooks
 	}
 )
 
 // NewHTTPNotificationHandler возвращает новый HTTPNotificationHandler для получения HTTP уведомлений от сервера Оплати.
 //   - publicKey - Публичный ключ, используемый для проверки подписи Server-Sign, полученный в личном кабинете Оплати.Бизнес.
 //   - paymentHandler - обработчик для выполнения каких-либо действий с полученным платежом.
-func NewHTTPNotificationHandler(publicKey string, paymentHandler PaymentNotificationHandler) (HTTPNotificationHandler, error) {
-	rawKey, err := base64.StdEncoding.DecodeString(publicKey)
+//   - opts - Дополнительные настройки: WithNotificationHooks
+func NewHTTPNotificationHandler(publicKey string, paymentHandler PaymentNotificationHandler, opts ...NotificationHandlerOpt) (HTTPNotificationHandler, error) {
+	rsaKey, err := ParsePublicKey(publicKey)
 	if err != nil {
-		return HTTPNotificationHandler{}, fmt.Errorf("public key base64 decoding failed: %w", err)
-	}
-
-	key, err := x509.ParsePKIXPublicKey(rawKey)
-	if err != nil {
-		return HTTPNotificationHandler{}, fmt.Errorf("public key parsing failed: %w", err)
-	}
-
-	rsaKey, ok := key.(*rsa.PublicKey)
-	if !ok {
-		return HTTPNotificationHandler{}, errors.New("provided public key is not RSA")
+		return HTTPNotificationHandler{}, err
 	}
 
 	if paymentHandler == nil {
 		return HTTPNotificationHandler{}, errors.New("nil handler is not allowed")
 	}
 
-	return HTTPNotificationHandler{
+	nh := HTTPNotificationHandler{
 		publicKey: rsaKey,
 		handler:   paymentHandler,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(&nh)
+	}
+
+	return nh, nil
+}
+
+// HandlePayment реализует PaymentNotificationHandler
+func (f PaymentNotificationHandlerFunc) HandlePayment(payment PaymentInfo) error {
+	return f(payment)
 }
 
 func (nh *HTTPNotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	decodedSignature, err := base64.StdEncoding.DecodeString(r.Header.Get("Server-Sign"))
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	status, err := nh.HandleNotification(r, body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		http.Error(w, err.Error(), status)
 		return
 	}
+}
 
-	sum := sha256.Sum256(body)
-
-	err = rsa.VerifyPKCS1v15(nh.publicKey, crypto.SHA256, sum[:], decodedSignature)
+// HandleNotification выполняет шаги ServeHTTP для уже прочитанного тела уведомления body и возвращает HTTP код ответа
+// серверу Оплати и ошибку, текст которой следует отправить в теле ответа. Из r используются контекст и заголовки,
+// r передается в NotificationHooks.SignatureFailed; r.Body не читается. Предназначен для адаптеров HTTP
+// фреймворков, не использующих http.Handler (см. пакеты ogin, oecho, ochi и ofiber).
+func (nh *HTTPNotificationHandler) HandleNotification(r *http.Request, body []byte) (int, error) {
+	err := nh.verify(r, body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		return
+		return http.StatusUnauthorized, err
 	}
 
-	var rawPaymentInfo paymentInfoResponse
-	err = json.Unmarshal(body, &rawPaymentInfo)
+	return nh.handleVerified(r, body)
+}
+
+// verify проверяет подпись уведомления body и вызывает NotificationHooks.SignatureFailed, если она неверна
+func (nh *HTTPNotificationHandler) verify(r *http.Request, body []byte) error {
+	err := VerifyNotification(nh.publicKey, body, r.Header.Get(ServerSignHeader))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		nh.signatureFailed(r, err)
 	}
+	return err
+}
 
-	paymentInfo, err := makePaymentInfoFromRaw(rawPaymentInfo)
+// handleVerified разбирает уведомление с уже проверенной подписью и передает его обработчику
+func (nh *HTTPNotificationHandler) handleVerified(r *http.Request, body []byte) (int, error) {
+	notification, err := DecodeNotification(body, r.Header)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, err
 	}
 
-	err = nh.handler.HandlePayment(paymentInfo)
+	err = DeliverNotification(r.Context(), nh.handler, notification)
+	nh.paymentReceived(r.Context(), notification.Payment, err)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, err
 	}
+
+	return http.StatusOK, nil
 }
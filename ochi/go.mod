@@ -0,0 +1,10 @@
+module github.com/oplati-by/go-acquiring/ochi
+
+go 1.23.8
+
+require github.com/oplati-by/go-acquiring v0.0.0-20261019104005-b0095f915717
+
+require (
+	github.com/go-chi/chi/v5 v5.3.2
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,27 @@
+// Package ochi подключает oacquiring.HTTPNotificationHandler к роутеру chi. oacquiring.HTTPNotificationHandler
+// реализует http.Handler, поэтому проверка подписи, коды ответов и хуки совпадают без преобразований.
+//
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, &Handler{})
+//	// ...
+//	router := chi.NewRouter()
+//	ochi.Register(router, "/oplati/notification", &handler)
+//
+// Параметры пути chi доступны через http.Request.PathValue, поэтому обработчик oacquiring.ClientPool.NotificationHandler
+// с oacquiring.CashboxKeyFromPath можно подключить так же:
+//
+//	handler, err := pool.NotificationHandler(key, &CashboxHandler{}, oacquiring.CashboxKeyFromPath("cashbox"))
+//	// ...
+//	router.Method(http.MethodPost, "/oplati/{cashbox}/notification", handler)
+package ochi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// Register подключает nh к router для POST запросов на pattern
+func Register(router chi.Router, pattern string, nh *oacquiring.HTTPNotificationHandler) {
+	router.Method(http.MethodPost, pattern, nh)
+}
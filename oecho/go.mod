@@ -0,0 +1,21 @@
+module github.com/oplati-by/go-acquiring/oecho
+
+go 1.23.8
+
+require (
+	github.com/labstack/echo/v4 v4.13.4
+	github.com/oplati-by/go-acquiring v0.0.0-20261019104005-b0095f915717
+)
+
+require (
+	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.2 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
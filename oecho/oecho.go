@@ -0,0 +1,33 @@
+// Package oecho подключает oacquiring.HTTPNotificationHandler к роутеру echo. Проверка подписи, коды ответов и хуки
+// совпадают с oacquiring.HTTPNotificationHandler.ServeHTTP.
+//
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, &Handler{})
+//	// ...
+//	e := echo.New()
+//	e.POST("/oplati/notification", oecho.Handler(&handler))
+package oecho
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// Handler возвращает echo.HandlerFunc, обрабатывающий уведомления от сервера Оплати с помощью nh
+func Handler(nh *oacquiring.HTTPNotificationHandler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.String(http.StatusUnauthorized, err.Error()+"\n")
+		}
+
+		status, err := nh.HandleNotification(c.Request(), body)
+		if err != nil {
+			return c.String(status, err.Error()+"\n")
+		}
+
+		return c.NoContent(status)
+	}
+}
@@ -0,0 +1,99 @@
+package oecho_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	oacquiring "github.com/oplati-by/go-acquiring"
+	"github.com/oplati-by/go-acquiring/oecho"
+)
+
+// newRouter возвращает функцию, отправляющую уведомление с подписью sign через роутер с обработчиком payments, и
+// закрытый ключ для подписи уведомлений
+func newRouter(payments oacquiring.PaymentNotificationHandler) (func(body []byte, sign string) int, *rsa.PrivateKey) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	publicKey, err := oacquiring.EncodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+
+	handler, err := oacquiring.NewHTTPNotificationHandler(publicKey, payments)
+	if err != nil {
+		panic(err)
+	}
+
+	e := echo.New()
+	e.POST("/oplati/notification", oecho.Handler(&handler))
+
+	return func(body []byte, sign string) int {
+		req := httptest.NewRequest(http.MethodPost, "/oplati/notification", bytes.NewReader(body))
+		req.Header.Set(oacquiring.ServerSignHeader, sign)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}, privateKey
+}
+
+func ExampleHandler() {
+	send, privateKey := newRouter(oacquiring.PaymentNotificationHandlerFunc(func(payment oacquiring.PaymentInfo) error {
+		fmt.Println("payment", payment.Id, payment.Status)
+		return nil
+	}))
+
+	body, _ := oacquiring.MarshalNotification(oacquiring.PaymentInfo{Id: 1234, Status: oacquiring.PaymentStatusDone})
+	sign, _ := oacquiring.SignNotification(privateKey, body)
+	fmt.Println(send(body, sign))
+
+	// Output:
+	// payment 1234 OK
+	// 200
+}
+
+func TestResponseCodes(t *testing.T) {
+	send, privateKey := newRouter(oacquiring.PaymentNotificationHandlerFunc(func(payment oacquiring.PaymentInfo) error {
+		if payment.Id == 500 {
+			return fmt.Errorf("handler failed")
+		}
+		return nil
+	}))
+
+	ok, _ := oacquiring.MarshalNotification(oacquiring.PaymentInfo{Id: 1, Status: oacquiring.PaymentStatusDone})
+	failing, _ := oacquiring.MarshalNotification(oacquiring.PaymentInfo{Id: 500, Status: oacquiring.PaymentStatusDone})
+	malformed := []byte("{")
+	sign := func(body []byte) string {
+		s, err := oacquiring.SignNotification(privateKey, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		body   []byte
+		sign   string
+		status int
+	}{
+		{"signed", ok, sign(ok), http.StatusOK},
+		{"missing signature", ok, "", http.StatusUnauthorized},
+		{"signature of other body", ok, sign(failing), http.StatusUnauthorized},
+		{"malformed body", malformed, sign(malformed), http.StatusBadRequest},
+		{"handler error", failing, sign(failing), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := send(tt.body, tt.sign); status != tt.status {
+				t.Errorf("got status %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// Package ofiber подключает oacquiring.HTTPNotificationHandler к fiber (fasthttp) без преобразования в http.Handler.
+// Проверка подписи, коды ответов и хуки совпадают с oacquiring.HTTPNotificationHandler.ServeHTTP; подпись проверяется
+// по исходному телу запроса.
+//
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, &Handler{})
+//	// ...
+//	app := fiber.New()
+//	app.Post("/oplati/notification", ofiber.Handler(&handler))
+package ofiber
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	oacquiring "github.com/oplati-by/go-acquiring"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// Handler возвращает fiber.Handler, обрабатывающий уведомления от сервера Оплати с помощью nh. В контекст запроса,
+// передаваемый в хуки, попадает fiber.Ctx.UserContext.
+func Handler(nh *oacquiring.HTTPNotificationHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		body := bytes.Clone(c.Request().Body())
+
+		var r http.Request
+		err := fasthttpadaptor.ConvertRequest(c.Context(), &r, true)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).SendString(err.Error() + "\n")
+		}
+
+		status, err := nh.HandleNotification(r.WithContext(c.UserContext()), body)
+		if err != nil {
+			return c.Status(status).SendString(err.Error() + "\n")
+		}
+
+		c.Status(status)
+		return nil
+	}
+}
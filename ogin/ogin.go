@@ -0,0 +1,35 @@
+// Package ogin подключает oacquiring.HTTPNotificationHandler к роутеру gin. Проверка подписи, коды ответов и хуки
+// совпадают с oacquiring.HTTPNotificationHandler.ServeHTTP.
+//
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, &Handler{})
+//	// ...
+//	router := gin.New()
+//	router.POST("/oplati/notification", ogin.Handler(&handler))
+package ogin
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// Handler возвращает gin.HandlerFunc, обрабатывающий уведомления от сервера Оплати с помощью nh
+func Handler(nh *oacquiring.HTTPNotificationHandler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusUnauthorized, err.Error()+"\n")
+			return
+		}
+
+		status, err := nh.HandleNotification(c.Request, body)
+		if err != nil {
+			c.String(status, err.Error()+"\n")
+			return
+		}
+
+		c.Status(status)
+	}
+}
@@ -0,0 +1,337 @@
+// Package oprometheus содержит prometheus.Collector с метриками работы oacquiring.Client и
+// oacquiring.HTTPNotificationHandler.
+//
+//	collector := oprometheus.NewCollector()
+//	prometheus.MustRegister(collector)
+//
+//	oplatiClient := oacquiring.NewClient(baseUrl, regNum, password, collector.ClientOpt())
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, &Handler{}, collector.NotificationHandlerOpt())
+package oprometheus
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// Collector - prometheus.Collector с метриками Оплати. Для инициализации используйте NewCollector.
+	//
+	// Метрики (с учетом Namespace):
+	//   - oplati_payments_created_total - количество платежей, созданных CreatePayment и CreatePOSPayment
+	//   - oplati_payments_created_amount_byn_total - сумма платежей, созданных CreatePayment и CreatePOSPayment, в BYN
+	//   - oplati_payments_reversed_total - количество выполненных возвратов
+	//   - oplati_payments_reversed_amount_byn_total - сумма выполненных возвратов в BYN
+	//   - oplati_api_request_duration_seconds - гистограмма длительности запросов к API по операциям
+	//   - oplati_api_requests_rejected_total - количество запросов, не отправленных из-за разомкнутого
+	//     автоматического выключателя, по операциям
+	//   - oplati_api_rate_limit_wait_seconds - гистограмма ожидания ограничителя частоты запросов по операциям
+	//   - oplati_payments_awaiting_notification - количество созданных платежей, по которым еще не получен конечный
+	//     статус. Платежи без конечного статуса перестают учитываться через WithAwaitingTTL
+	//   - oplati_notifications_total - количество обработанных уведомлений по статусам платежа
+	//   - oplati_notification_signature_failures_total - количество уведомлений с неверной подписью Server-Sign
+	Collector struct {
+		paymentsCreated        prometheus.Counter
+		paymentsCreatedAmount  prometheus.Counter
+		paymentsReversed       prometheus.Counter
+		paymentsReversedAmount prometheus.Counter
+		requestDuration        *prometheus.HistogramVec
+		requestsRejected       *prometheus.CounterVec
+		rateLimitWait          *prometheus.HistogramVec
+		awaitingNotification   prometheus.Gauge
+		notifications          *prometheus.CounterVec
+		signatureFailures      prometheus.Counter
+
+		mu          sync.Mutex
+		inFlight    map[int64]time.Time // Время создания платежей, ожидающих конечного статуса
+		awaitingTTL time.Duration
+		lastEvicted time.Time
+	}
+
+	// Opt - дополнительные параметры Collector
+	Opt func(*options)
+
+	options struct {
+		namespace   string
+		constLabels prometheus.Labels
+		buckets     []float64
+		awaitingTTL time.Duration
+	}
+)
+
+// defaultAwaitingTTL - время, после которого платеж без конечного статуса перестает учитываться в
+// payments_awaiting_notification
+const defaultAwaitingTTL = time.Hour
+
+// WithNamespace - переопределяет префикс метрик. По умолчанию oplati
+func WithNamespace(namespace string) Opt {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithConstLabels - добавляет постоянные метки ко всем метрикам, например номер кассы
+func WithConstLabels(labels prometheus.Labels) Opt {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// WithBuckets - переопределяет границы гистограммы длительности запросов. По умолчанию prometheus.DefBuckets
+func WithBuckets(buckets []float64) Opt {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// WithAwaitingTTL - время, после которого созданный платеж, по которому не получен конечный статус (например,
+// уведомление потеряно), перестает учитываться в payments_awaiting_notification. По умолчанию 1 час
+func WithAwaitingTTL(ttl time.Duration) Opt {
+	return func(o *options) {
+		o.awaitingTTL = ttl
+	}
+}
+
+// NewCollector возвращает новый Collector. Перед использованием его необходимо зарегистрировать в
+// prometheus.Registerer.
+func NewCollector(opts ...Opt) *Collector {
+	o := options{
+		namespace:   "oplati",
+		buckets:     prometheus.DefBuckets,
+		awaitingTTL: defaultAwaitingTTL,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.awaitingTTL <= 0 {
+		o.awaitingTTL = defaultAwaitingTTL
+	}
+
+	return &Collector{
+		paymentsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "payments_created_total",
+			Help:        "Number of payments created via CreatePayment or CreatePOSPayment.",
+			ConstLabels: o.constLabels,
+		}),
+		paymentsCreatedAmount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "payments_created_amount_byn_total",
+			Help:        "Total amount of payments created via CreatePayment or CreatePOSPayment, BYN.",
+			ConstLabels: o.constLabels,
+		}),
+		paymentsReversed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "payments_reversed_total",
+			Help:        "Number of reversals made via ReversePayment.",
+			ConstLabels: o.constLabels,
+		}),
+		paymentsReversedAmount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "payments_reversed_amount_byn_total",
+			Help:        "Total amount of reversals made via ReversePayment, BYN.",
+			ConstLabels: o.constLabels,
+		}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "api_request_duration_seconds",
+			Help:        "Duration of Oplati API requests.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"operation", "result"}),
+		requestsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "api_requests_rejected_total",
+			Help:        "Number of Oplati API requests not sent because the circuit breaker is open.",
+			ConstLabels: o.constLabels,
+		}, []string{"operation"}),
+		rateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "api_rate_limit_wait_seconds",
+			Help:        "Time Oplati API requests spent waiting for the rate limiter.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"operation"}),
+		awaitingNotification: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   o.namespace,
+			Name:        "payments_awaiting_notification",
+			Help:        "Number of created payments without a final status yet.",
+			ConstLabels: o.constLabels,
+		}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "notifications_total",
+			Help:        "Number of verified notifications by payment status and handling result.",
+			ConstLabels: o.constLabels,
+		}, []string{"status", "result"}),
+		signatureFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "notification_signature_failures_total",
+			Help:        "Number of notifications rejected because of missing or invalid Server-Sign.",
+			ConstLabels: o.constLabels,
+		}),
+		inFlight:    make(map[int64]time.Time),
+		awaitingTTL: o.awaitingTTL,
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.paymentsCreated.Describe(ch)
+	c.paymentsCreatedAmount.Describe(ch)
+	c.paymentsReversed.Describe(ch)
+	c.paymentsReversedAmount.Describe(ch)
+	c.requestDuration.Describe(ch)
+	c.requestsRejected.Describe(ch)
+	c.rateLimitWait.Describe(ch)
+	c.awaitingNotification.Describe(ch)
+	c.notifications.Describe(ch)
+	c.signatureFailures.Describe(ch)
+}
+
+// Collect реализует prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	c.evictLocked(time.Now())
+	c.mu.Unlock()
+
+	c.paymentsCreated.Collect(ch)
+	c.paymentsCreatedAmount.Collect(ch)
+	c.paymentsReversed.Collect(ch)
+	c.paymentsReversedAmount.Collect(ch)
+	c.requestDuration.Collect(ch)
+	c.requestsRejected.Collect(ch)
+	c.rateLimitWait.Collect(ch)
+	c.awaitingNotification.Collect(ch)
+	c.notifications.Collect(ch)
+	c.signatureFailures.Collect(ch)
+}
+
+// ClientHooks возвращает oacquiring.ClientHooks, обновляющие метрики Collector
+func (c *Collector) ClientHooks() oacquiring.ClientHooks {
+	return oacquiring.ClientHooks{
+		RequestDone:         c.requestDone,
+		RequestRejected:     c.requestRejected,
+		RateLimitWaited:     c.rateLimitWaited,
+		PaymentCreated:      c.paymentCreated,
+		PaymentReversed:     c.paymentReversed,
+		PaymentInfoReceived: c.paymentInfoReceived,
+	}
+}
+
+// ClientOpt возвращает oacquiring.ClientOpt для подключения Collector к oacquiring.Client
+func (c *Collector) ClientOpt() oacquiring.ClientOpt {
+	return oacquiring.WithClientHooks(c.ClientHooks())
+}
+
+// NotificationHooks возвращает oacquiring.NotificationHooks, обновляющие метрики Collector
+func (c *Collector) NotificationHooks() oacquiring.NotificationHooks {
+	return oacquiring.NotificationHooks{
+		SignatureFailed: c.signatureFailed,
+		PaymentReceived: c.paymentReceived,
+	}
+}
+
+// NotificationHandlerOpt возвращает oacquiring.NotificationHandlerOpt для подключения Collector к
+// oacquiring.HTTPNotificationHandler
+func (c *Collector) NotificationHandlerOpt() oacquiring.NotificationHandlerOpt {
+	return oacquiring.WithNotificationHooks(c.NotificationHooks())
+}
+
+func (c *Collector) requestDone(_ context.Context, op oacquiring.Operation, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	c.requestDuration.WithLabelValues(string(op), result).Observe(duration.Seconds())
+}
+
+func (c *Collector) requestRejected(_ context.Context, op oacquiring.Operation, _ error) {
+	c.requestsRejected.WithLabelValues(string(op)).Inc()
+}
+
+func (c *Collector) rateLimitWaited(_ context.Context, op oacquiring.Operation, wait time.Duration, _ error) {
+	c.rateLimitWait.WithLabelValues(string(op)).Observe(wait.Seconds())
+}
+
+func (c *Collector) paymentCreated(_ context.Context, payment oacquiring.Payment, result oacquiring.SuccessfulPayment) {
+	c.paymentsCreated.Inc()
+	c.paymentsCreatedAmount.Add(itemsAmount(payment.Items))
+
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictLocked(now)
+	if _, ok := c.inFlight[result.PaymentId]; !ok {
+		c.inFlight[result.PaymentId] = now
+		c.awaitingNotification.Inc()
+	}
+}
+
+func (c *Collector) paymentReversed(_ context.Context, _ int64, reversal oacquiring.PaymentReversal, _ oacquiring.PaymentInfo) {
+	c.paymentsReversed.Inc()
+	c.paymentsReversedAmount.Add(itemsAmount(reversal.Items))
+}
+
+func (c *Collector) paymentInfoReceived(_ context.Context, _ oacquiring.Operation, info oacquiring.PaymentInfo) {
+	c.resolve(info)
+}
+
+func (c *Collector) signatureFailed(_ *http.Request, _ error) {
+	c.signatureFailures.Inc()
+}
+
+func (c *Collector) paymentReceived(_ context.Context, info oacquiring.PaymentInfo, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	c.notifications.WithLabelValues(info.Status.String(), result).Inc()
+
+	if err == nil {
+		c.resolve(info)
+	}
+}
+
+// resolve убирает платеж из ожидающих, если для него получен конечный статус
+func (c *Collector) resolve(info oacquiring.PaymentInfo) {
+	if info.Status == oacquiring.PaymentStatusInProgress {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.inFlight[info.Id]; ok {
+		delete(c.inFlight, info.Id)
+		c.awaitingNotification.Dec()
+	}
+}
+
+// evictLocked убирает из ожидающих платежи, созданные раньше чем awaitingTTL назад. Выполняется не чаще раза в
+// awaitingTTL/10. Вызывается под c.mu.
+func (c *Collector) evictLocked(now time.Time) {
+	if now.Sub(c.lastEvicted) < c.awaitingTTL/10 {
+		return
+	}
+	c.lastEvicted = now
+
+	for id, created := range c.inFlight {
+		if now.Sub(created) >= c.awaitingTTL {
+			delete(c.inFlight, id)
+			c.awaitingNotification.Dec()
+		}
+	}
+}
+
+func itemsAmount(items []oacquiring.PaymentItem) float64 {
+	var sum int64
+	for _, item := range items {
+		sum += item.Cost
+	}
+	return float64(sum) / 100
+}
@@ -5,11 +5,91 @@ import "net/http"
 type (
 	// ClientOpt - дополнительные параметры Client
 	ClientOpt func(*Client)
+
+	// NotificationHandlerOpt - дополнительные параметры HTTPNotificationHandler
+	NotificationHandlerOpt func(*HTTPNotificationHandler)
 )
 
-// WithCustomHTTPClient - позволяет переопределить http.Client, используемый для отправки запросов к серверу Оплати
+// WithCustomHTTPClient - позволяет переопределить http.Client, используемый для отправки запросов к серверу Оплати.
+// Для перехвата запросов используйте WithMiddleware
 func WithCustomHTTPClient(client http.Client) ClientOpt {
 	return func(c *Client) {
 		c.httpClient = client
 	}
 }
+
+// WithCredentialsProvider - позволяет получать регистрационный номер и пароль кассы из CredentialsProvider перед
+// каждым запросом вместо значений, переданных в NewClient
+func WithCredentialsProvider(provider CredentialsProvider) ClientOpt {
+	return func(c *Client) {
+		c.credentials = provider
+	}
+}
+
+// WithRetry - включает повтор запросов, завершившихся временной ошибкой, согласно RetryPolicy
+func WithRetry(policy RetryPolicy) ClientOpt {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithMiddleware - добавляет Middleware в цепочку обработки запросов. Может быть указан несколько раз. Первый
+// добавленный Middleware вызывается первым, последний - непосредственно перед http.Client
+func WithMiddleware(middlewares ...Middleware) ClientOpt {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithRateLimit - ограничивает частоту и количество одновременных запросов для операций ops (или для всех операций,
+// для которых не заданы отдельные ограничения, если ops не указаны). Запросы, превышающие ограничения, ожидают
+// своей очереди до отмены контекста. Ограничения действуют в рамках одного Client (и его копий).
+func WithRateLimit(limit RateLimit, ops ...Operation) ClientOpt {
+	return func(c *Client) {
+		if c.limiters == nil {
+			c.limiters = &rateLimiters{byOperation: make(map[Operation]*limiter)}
+		}
+
+		if len(ops) == 0 {
+			c.limiters.fallback = newLimiter(limit)
+			return
+		}
+		for _, op := range ops {
+			c.limiters.byOperation[op] = newLimiter(limit)
+		}
+	}
+}
+
+// WithCircuitBreaker - включает автоматический выключатель для операций ops. Если ops не указаны, один общий
+// выключатель используется для всех операций, для которых не заданы отдельные настройки. Пока выключатель разомкнут,
+// операции сразу возвращают ErrCircuitOpen. Состояние выключателя общее для Client и его копий.
+func WithCircuitBreaker(settings CircuitBreakerSettings, ops ...Operation) ClientOpt {
+	return func(c *Client) {
+		if c.breakers == nil {
+			c.breakers = &circuitBreakers{byOperation: make(map[Operation]*circuitBreaker)}
+		}
+
+		if len(ops) == 0 {
+			c.breakers.fallback = newCircuitBreaker(settings)
+			return
+		}
+		for _, op := range ops {
+			c.breakers.byOperation[op] = newCircuitBreaker(settings)
+		}
+	}
+}
+
+// WithClientHooks - добавляет набор ClientHooks. Может быть указан несколько раз, хуки вызываются в порядке добавления
+func WithClientHooks(hooks ClientHooks) ClientOpt {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, hooks)
+	}
+}
+
+// WithNotificationHooks - добавляет набор NotificationHooks. Может быть указан несколько раз, хуки вызываются в порядке
+// добавления
+func WithNotificationHooks(hooks NotificationHooks) NotificationHandlerOpt {
+	return func(nh *HTTPNotificationHandler) {
+		nh.hooks = append(nh.hooks, hooks)
+	}
+}
@@ -0,0 +1,493 @@
+// Package outbox реализует шаблон transactional outbox для событий изменения платежей Оплати. События записываются
+// в таблицу oplati_outbox в той же транзакции database/sql, что и бизнес-изменения приложения, а Relay публикует их
+// через Publisher с гарантией доставки хотя бы один раз и сохранением порядка событий одного платежа.
+//
+//	err := outbox.Migrate(ctx, db, outbox.Postgres)
+//	// ...
+//	box := outbox.New(db, outbox.Postgres)
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key,
+//		box.NotificationHandler(func(ctx context.Context, tx *sql.Tx, payment oacquiring.PaymentInfo) error {
+//			_, err := tx.ExecContext(ctx, "UPDATE orders SET paid = $1 WHERE id = $2", ...)
+//			return err
+//		}))
+//	// ...
+//	relay := outbox.NewRelay(box, outbox.NewWebhookPublisher("https://example.com/events"))
+//	go relay.Run(ctx)
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+const (
+	eventsTable     = "oplati_outbox"
+	migrationsTable = "oplati_outbox_migrations"
+
+	eventColumns = "id, payment_id, type, status, amount, order_number, purse_public_id, created_date, paid_date, created_at, attempts, last_error"
+)
+
+var (
+	// Postgres - диалект PostgreSQL
+	Postgres = Dialect{
+		name:        "postgres",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		migrations: [][]string{
+			{
+				`CREATE TABLE IF NOT EXISTS oplati_outbox (
+					id BIGSERIAL PRIMARY KEY,
+					payment_id BIGINT NOT NULL,
+					type INTEGER NOT NULL,
+					status INTEGER NOT NULL,
+					amount BIGINT NOT NULL,
+					order_number TEXT NOT NULL DEFAULT '',
+					purse_public_id TEXT NOT NULL DEFAULT '',
+					created_date TIMESTAMPTZ NULL,
+					paid_date TIMESTAMPTZ NULL,
+					created_at TIMESTAMPTZ NOT NULL,
+					published_at TIMESTAMPTZ NULL,
+					attempts INTEGER NOT NULL DEFAULT 0,
+					last_error TEXT NOT NULL DEFAULT ''
+				)`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_unpublished_idx ON oplati_outbox (id) WHERE published_at IS NULL`,
+			},
+			{
+				`ALTER TABLE oplati_outbox ADD COLUMN next_attempt_at TIMESTAMPTZ NULL`,
+				`ALTER TABLE oplati_outbox ADD COLUMN dead_at TIMESTAMPTZ NULL`,
+				`DROP INDEX IF EXISTS oplati_outbox_unpublished_idx`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_pending_idx ON oplati_outbox (payment_id, id)
+					WHERE published_at IS NULL AND dead_at IS NULL`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_dead_idx ON oplati_outbox (id) WHERE dead_at IS NOT NULL`,
+			},
+			{
+				`DROP INDEX IF EXISTS oplati_outbox_pending_idx`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_pending_idx ON oplati_outbox (id)
+					WHERE published_at IS NULL AND dead_at IS NULL`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_payment_idx ON oplati_outbox (payment_id, id)`,
+			},
+		},
+	}
+
+	// SQLite - диалект SQLite
+	SQLite = Dialect{
+		name:        "sqlite",
+		placeholder: func(int) string { return "?" },
+		migrations: [][]string{
+			{
+				`CREATE TABLE IF NOT EXISTS oplati_outbox (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					payment_id INTEGER NOT NULL,
+					type INTEGER NOT NULL,
+					status INTEGER NOT NULL,
+					amount INTEGER NOT NULL,
+					order_number TEXT NOT NULL DEFAULT '',
+					purse_public_id TEXT NOT NULL DEFAULT '',
+					created_date DATETIME NULL,
+					paid_date DATETIME NULL,
+					created_at DATETIME NOT NULL,
+					published_at DATETIME NULL,
+					attempts INTEGER NOT NULL DEFAULT 0,
+					last_error TEXT NOT NULL DEFAULT ''
+				)`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_unpublished_idx ON oplati_outbox (id) WHERE published_at IS NULL`,
+			},
+			{
+				`ALTER TABLE oplati_outbox ADD COLUMN next_attempt_at DATETIME NULL`,
+				`ALTER TABLE oplati_outbox ADD COLUMN dead_at DATETIME NULL`,
+				`DROP INDEX IF EXISTS oplati_outbox_unpublished_idx`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_pending_idx ON oplati_outbox (payment_id, id)
+					WHERE published_at IS NULL AND dead_at IS NULL`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_dead_idx ON oplati_outbox (id) WHERE dead_at IS NOT NULL`,
+			},
+			{
+				`DROP INDEX IF EXISTS oplati_outbox_pending_idx`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_pending_idx ON oplati_outbox (id)
+					WHERE published_at IS NULL AND dead_at IS NULL`,
+				`CREATE INDEX IF NOT EXISTS oplati_outbox_payment_idx ON oplati_outbox (payment_id, id)`,
+			},
+		},
+	}
+)
+
+var (
+	// ErrEventNotFound - событие не найдено
+	ErrEventNotFound = errors.New("event not found")
+	// ErrEventSuperseded - более позднее событие того же платежа уже опубликовано, поэтому повторная публикация
+	// нарушит порядок событий платежа
+	ErrEventSuperseded = errors.New("newer event of the payment is already published")
+)
+
+type (
+	// Dialect - диалект SQL. Варианты: Postgres, SQLite
+	Dialect struct {
+		name        string
+		placeholder func(n int) string
+		migrations  [][]string
+	}
+
+	// Event - событие изменения платежа
+	Event struct {
+		Id        int64                  // Порядковый номер события в outbox. Используйте для дедупликации
+		Payment   oacquiring.PaymentInfo // Состояние платежа на момент события
+		CreatedAt time.Time              // Время записи события
+		Attempts  int                    // Количество неудачных попыток публикации
+		LastError string                 // Ошибка последней неудачной попытки публикации
+	}
+
+	// Execer - *sql.Tx или *sql.DB
+	Execer interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	}
+
+	// TxHandlerFunc - обработчик уведомления, выполняющий бизнес-изменения в транзакции tx
+	TxHandlerFunc func(ctx context.Context, tx *sql.Tx, payment oacquiring.PaymentInfo) error
+
+	// Outbox - таблица событий. Для инициализации используйте New.
+	Outbox struct {
+		db      *sql.DB
+		dialect Dialect
+	}
+
+	txNotificationHandler struct {
+		outbox *Outbox
+		handle TxHandlerFunc
+	}
+)
+
+func (d Dialect) String() string {
+	return d.name
+}
+
+// query заменяет плейсхолдеры "?" в query на плейсхолдеры диалекта
+func (d Dialect) query(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString(d.placeholder(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Migrate создает или обновляет таблицу oplati_outbox. Примененные миграции записываются в таблицу
+// oplati_outbox_migrations, поэтому Migrate можно вызывать при каждом запуске приложения.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("migrations table creation failed: %w", err)
+	}
+
+	var version int
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM `+migrationsTable).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("schema version reading failed: %w", err)
+	}
+
+	for i := version; i < len(dialect.migrations); i++ {
+		err = applyMigration(ctx, db, dialect, i+1)
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, dialect Dialect, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, statement := range dialect.migrations[version-1] {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, dialect.query(`INSERT INTO `+migrationsTable+` (version) VALUES (?)`), version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// New возвращает новый Outbox
+func New(db *sql.DB, dialect Dialect) *Outbox {
+	return &Outbox{db: db, dialect: dialect}
+}
+
+// Enqueue записывает событие изменения платежа. tx должна быть транзакцией, в которой выполняются бизнес-изменения:
+// событие будет опубликовано только после ее фиксации.
+//
+// Порядок публикации определяется порядком записи событий. Чтобы события одного платежа из параллельных транзакций не
+// перемешались, блокируйте в транзакции связанную с платежом строку (например, заказ) до вызова Enqueue.
+func (o *Outbox) Enqueue(ctx context.Context, tx Execer, payment oacquiring.PaymentInfo) error {
+	_, err := tx.ExecContext(ctx, o.dialect.query(`INSERT INTO `+eventsTable+`
+		(payment_id, type, status, amount, order_number, purse_public_id, created_date, paid_date, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		payment.Id, int(payment.Type), int(payment.Status), payment.Sum, payment.OrderNumber, payment.PursePublicId,
+		nullTime(payment.CreatedDate), nullTime(payment.PaidDate), time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("event insertion failed: %w", err)
+	}
+
+	return nil
+}
+
+// NotificationHandler возвращает oacquiring.PaymentNotificationHandler, который открывает транзакцию, вызывает handle,
+// записывает событие и фиксирует транзакцию. Событие записывается, только если статус платежа отличается от статуса в
+// последнем событии этого платежа, поэтому повторная доставка уведомления не создает нового события. Если handle или
+// запись события завершились ошибкой, транзакция откатывается, а ошибка возвращается серверу Оплати для повторной
+// отправки уведомления.
+func (o *Outbox) NotificationHandler(handle TxHandlerFunc) oacquiring.PaymentNotificationHandler {
+	return &txNotificationHandler{outbox: o, handle: handle}
+}
+
+func (h *txNotificationHandler) HandlePayment(payment oacquiring.PaymentInfo) error {
+	return h.ReceiveNotification(context.Background(), oacquiring.Notification{Payment: payment})
+}
+
+// ReceiveNotification реализует oacquiring.NotificationReceiver, чтобы транзакция использовала контекст запроса
+func (h *txNotificationHandler) ReceiveNotification(ctx context.Context, notification oacquiring.Notification) error {
+	payment := notification.Payment
+
+	tx, err := h.outbox.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if h.handle != nil {
+		err = h.handle(ctx, tx, payment)
+		if err != nil {
+			return err
+		}
+	}
+
+	changed, err := h.outbox.statusChanged(ctx, tx, payment)
+	if err != nil {
+		return err
+	}
+	if changed {
+		err = h.outbox.Enqueue(ctx, tx, payment)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// statusChanged возвращает true, если у платежа нет событий или статус в его последнем событии отличается от
+// payment.Status
+func (o *Outbox) statusChanged(ctx context.Context, tx *sql.Tx, payment oacquiring.PaymentInfo) (bool, error) {
+	var status int
+	err := tx.QueryRowContext(ctx, o.dialect.query(`SELECT status FROM `+eventsTable+`
+		WHERE payment_id = ?
+		ORDER BY id DESC
+		LIMIT 1`), payment.Id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("last event query failed: %w", err)
+	}
+
+	return oacquiring.PaymentStatus(status) != payment.Status, nil
+}
+
+// Purge удаляет события, опубликованные раньше before, и возвращает количество удаленных событий. Последнее событие
+// каждого платежа сохраняется: по нему NotificationHandler определяет, изменился ли статус платежа.
+func (o *Outbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := o.db.ExecContext(ctx, o.dialect.query(`DELETE FROM `+eventsTable+`
+		WHERE published_at IS NOT NULL AND published_at < ?
+			AND EXISTS (
+				SELECT 1 FROM `+eventsTable+` n
+				WHERE n.payment_id = `+eventsTable+`.payment_id AND n.id > `+eventsTable+`.id
+			)`), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("events deletion failed: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeadLetters возвращает не более limit событий (все, если limit <= 0), публикация которых прекращена после
+// исчерпания попыток (см. WithMaxAttempts), в порядке записи. Пока событие находится в DeadLetters, следующие события
+// того же платежа не публикуются; верните его в очередь через Requeue или удалите через Discard.
+func (o *Outbox) DeadLetters(ctx context.Context, limit int) ([]Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM ` + eventsTable + ` WHERE dead_at IS NOT NULL ORDER BY id`
+	var args []any
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := o.db.QueryContext(ctx, o.dialect.query(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("events query failed: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+// Requeue возвращает событие из списка DeadLetters в очередь публикации со сброшенным счетчиком попыток. Если такого
+// события среди DeadLetters нет, возвращается ErrEventNotFound. Если более позднее событие того же платежа уже
+// опубликовано (например, событие попало в DeadLetters до обновления пакета), возвращается ErrEventSuperseded:
+// такое событие можно только удалить через Discard.
+func (o *Outbox) Requeue(ctx context.Context, eventId int64) error {
+	result, err := o.db.ExecContext(ctx, o.dialect.query(`UPDATE `+eventsTable+`
+		SET dead_at = NULL, next_attempt_at = NULL, attempts = 0
+		WHERE id = ? AND dead_at IS NOT NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM `+eventsTable+` n
+				WHERE n.payment_id = `+eventsTable+`.payment_id AND n.id > `+eventsTable+`.id
+					AND n.published_at IS NOT NULL
+			)`), eventId)
+	if err != nil {
+		return fmt.Errorf("event %d update failed: %w", eventId, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("event %d update failed: %w", eventId, err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var dead bool
+	err = o.db.QueryRowContext(ctx, o.dialect.query(`SELECT dead_at IS NOT NULL FROM `+eventsTable+` WHERE id = ?`),
+		eventId).Scan(&dead)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !dead {
+		return ErrEventNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("event %d query failed: %w", eventId, err)
+	}
+
+	return ErrEventSuperseded
+}
+
+// Discard удаляет событие из списка DeadLetters без публикации, после чего публикуются следующие события того же
+// платежа. Если такого события среди DeadLetters нет, возвращается ErrEventNotFound.
+func (o *Outbox) Discard(ctx context.Context, eventId int64) error {
+	result, err := o.db.ExecContext(ctx, o.dialect.query(`DELETE FROM `+eventsTable+`
+		WHERE id = ? AND dead_at IS NOT NULL`), eventId)
+	if err != nil {
+		return fmt.Errorf("event %d deletion failed: %w", eventId, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("event %d deletion failed: %w", eventId, err)
+	}
+	if affected == 0 {
+		return ErrEventNotFound
+	}
+
+	return nil
+}
+
+// pending возвращает не более limit событий, готовых к публикации, в порядке записи. Для каждого платежа возвращается
+// только самое раннее неопубликованное событие, и только если время следующей попытки уже наступило и у платежа нет
+// более раннего события в DeadLetters, поэтому события одного платежа публикуются по порядку, а откладываемые события
+// не занимают места в партии.
+func (o *Outbox) pending(ctx context.Context, now time.Time, limit int) ([]Event, error) {
+	rows, err := o.db.QueryContext(ctx, o.dialect.query(`SELECT `+eventColumns+` FROM `+eventsTable+` e
+		WHERE e.published_at IS NULL AND e.dead_at IS NULL
+			AND (e.next_attempt_at IS NULL OR e.next_attempt_at <= ?)
+			AND NOT EXISTS (
+				SELECT 1 FROM `+eventsTable+` p
+				WHERE p.payment_id = e.payment_id AND p.id < e.id AND p.published_at IS NULL
+			)
+		ORDER BY e.id
+		LIMIT ?`), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("events query failed: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]Event, error) {
+	defer func() { _ = rows.Close() }()
+
+	var events []Event
+	for rows.Next() {
+		var (
+			event                 Event
+			paymentType, status   int
+			createdDate, paidDate sql.NullTime
+		)
+		err := rows.Scan(&event.Id, &event.Payment.Id, &paymentType, &status, &event.Payment.Sum,
+			&event.Payment.OrderNumber, &event.Payment.PursePublicId, &createdDate, &paidDate, &event.CreatedAt,
+			&event.Attempts, &event.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("event scanning failed: %w", err)
+		}
+
+		event.Payment.Type = oacquiring.PaymentType(paymentType)
+		event.Payment.Status = oacquiring.PaymentStatus(status)
+		event.Payment.CreatedDate = createdDate.Time
+		event.Payment.PaidDate = paidDate.Time
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("events query failed: %w", err)
+	}
+
+	return events, nil
+}
+
+func (o *Outbox) markPublished(ctx context.Context, eventId int64) error {
+	_, err := o.db.ExecContext(ctx, o.dialect.query(`UPDATE `+eventsTable+` SET published_at = ? WHERE id = ?`),
+		time.Now().UTC(), eventId)
+	if err != nil {
+		return fmt.Errorf("event %d update failed: %w", eventId, err)
+	}
+	return nil
+}
+
+// markFailed записывает неудачную попытку публикации. Следующая попытка будет выполнена не раньше nextAttempt; если
+// dead == true, событие перемещается в DeadLetters.
+func (o *Outbox) markFailed(ctx context.Context, eventId int64, publishErr error, nextAttempt time.Time, dead bool) error {
+	var deadAt sql.NullTime
+	if dead {
+		deadAt = nullTime(time.Now())
+	}
+
+	_, err := o.db.ExecContext(ctx, o.dialect.query(`UPDATE `+eventsTable+`
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?, dead_at = ?
+		WHERE id = ?`), publishErr.Error(), nextAttempt.UTC(), deadAt, eventId)
+	if err != nil {
+		return fmt.Errorf("event %d update failed: %w", eventId, err)
+	}
+	return nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
@@ -0,0 +1,170 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventIdHeader - заголовок с Event.Id в запросах WebhookPublisher
+const EventIdHeader = "X-Oplati-Event-Id"
+
+type (
+	// Publisher - получатель событий. Publish может быть вызван для одного события несколько раз, поэтому получатель
+	// должен обрабатывать повторы (например, по Event.Id).
+	Publisher interface {
+		Publish(ctx context.Context, event Event) error
+	}
+
+	// PublisherFunc - адаптер, позволяющий использовать функцию как Publisher
+	PublisherFunc func(ctx context.Context, event Event) error
+
+	// MemoryPublisher - Publisher, сохраняющий события в памяти. Подходит для тестов. Для инициализации используйте
+	// NewMemoryPublisher.
+	MemoryPublisher struct {
+		mu     sync.Mutex
+		events []Event
+	}
+
+	// WebhookPublisher - Publisher, отправляющий события POST-запросом в формате JSON. Ответ со статусом, отличным от
+	// 2xx, считается ошибкой. Для инициализации используйте NewWebhookPublisher.
+	WebhookPublisher struct {
+		url        string
+		httpClient *http.Client
+		header     http.Header
+	}
+
+	// WebhookOpt - дополнительные параметры WebhookPublisher
+	WebhookOpt func(*WebhookPublisher)
+
+	webhookEvent struct {
+		EventId       int64      `json:"eventId"`
+		PaymentId     int64      `json:"paymentId"`
+		Type          int        `json:"type"`
+		Status        int        `json:"status"`
+		Sum           int64      `json:"sum"`
+		OrderNumber   string     `json:"orderNumber"`
+		PursePublicId string     `json:"pursePublicId,omitempty"`
+		CreatedDate   *time.Time `json:"createdDate,omitempty"`
+		PaidDate      *time.Time `json:"paidDate,omitempty"`
+		CreatedAt     time.Time  `json:"createdAt"`
+	}
+)
+
+// Publish реализует Publisher
+func (f PublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// NewMemoryPublisher возвращает новый MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish реализует Publisher
+func (m *MemoryPublisher) Publish(_ context.Context, event Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+	return nil
+}
+
+// Events возвращает опубликованные события в порядке публикации
+func (m *MemoryPublisher) Events() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := make([]Event, len(m.events))
+	copy(events, m.events)
+	return events
+}
+
+// WithWebhookHTTPClient - позволяет переопределить http.Client. По умолчанию используется клиент с таймаутом 10 секунд
+func WithWebhookHTTPClient(client *http.Client) WebhookOpt {
+	return func(p *WebhookPublisher) {
+		p.httpClient = client
+	}
+}
+
+// WithWebhookHeader - добавляет заголовок к каждому запросу (например, для авторизации)
+func WithWebhookHeader(key, value string) WebhookOpt {
+	return func(p *WebhookPublisher) {
+		p.header.Add(key, value)
+	}
+}
+
+// NewWebhookPublisher возвращает новый WebhookPublisher, отправляющий события на url
+func NewWebhookPublisher(url string, opts ...WebhookOpt) *WebhookPublisher {
+	p := &WebhookPublisher{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		header:     make(http.Header),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Publish реализует Publisher
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(newWebhookEvent(event))
+	if err != nil {
+		return fmt.Errorf("event marshalling failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request initialization failed: %w", err)
+	}
+	for key, values := range p.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIdHeader, strconv.FormatInt(event.Id, 10))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request execution failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func newWebhookEvent(event Event) webhookEvent {
+	payment := event.Payment
+	return webhookEvent{
+		EventId:       event.Id,
+		PaymentId:     payment.Id,
+		Type:          int(payment.Type),
+		Status:        int(payment.Status),
+		Sum:           payment.Sum,
+		OrderNumber:   payment.OrderNumber,
+		PursePublicId: payment.PursePublicId,
+		CreatedDate:   timePtr(payment.CreatedDate),
+		PaidDate:      timePtr(payment.PaidDate),
+		CreatedAt:     event.CreatedAt,
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,202 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultPollInterval   = time.Second
+	defaultBatchSize      = 100
+	defaultMaxAttempts    = 10
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 10 * time.Minute
+)
+
+type (
+	// Relay публикует события из Outbox через Publisher. Событие помечается опубликованным только после успешного
+	// Publish, поэтому при сбое оно будет опубликовано повторно (доставка хотя бы один раз). Если публикация события
+	// не удалась, следующая попытка выполняется с экспоненциальной задержкой (WithBackoff), а следующие события того же
+	// платежа откладываются до ее успеха. После WithMaxAttempts неудачных попыток событие перемещается в
+	// Outbox.DeadLetters, а события его платежа не публикуются до Outbox.Requeue или Outbox.Discard.
+	//
+	// Для сохранения порядка событий запускайте не более одного Relay на Outbox. Для инициализации используйте
+	// NewRelay.
+	Relay struct {
+		outbox       *Outbox
+		publisher    Publisher
+		pollInterval time.Duration
+		batchSize    int
+		maxAttempts  int
+		backoff      time.Duration
+		maxBackoff   time.Duration
+		onError      func(Event, error)
+		onDead       func(Event, error)
+		onStoreError func(error)
+	}
+
+	// RelayOpt - дополнительные параметры Relay
+	RelayOpt func(*Relay)
+)
+
+// WithPollInterval - интервал опроса таблицы событий, если неопубликованных событий нет. По умолчанию 1 секунда
+func WithPollInterval(interval time.Duration) RelayOpt {
+	return func(r *Relay) {
+		r.pollInterval = interval
+	}
+}
+
+// WithBatchSize - максимальное количество событий, читаемых за один запрос. По умолчанию 100
+func WithBatchSize(size int) RelayOpt {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithMaxAttempts - количество неудачных попыток публикации, после которого событие перемещается в
+// Outbox.DeadLetters. По умолчанию 10
+func WithMaxAttempts(attempts int) RelayOpt {
+	return func(r *Relay) {
+		r.maxAttempts = attempts
+	}
+}
+
+// WithBackoff - задержка перед повторной публикацией события после первой неудачной попытки. Каждая следующая
+// задержка вдвое больше предыдущей, но не больше maxBackoff. По умолчанию 1 секунда и 10 минут
+func WithBackoff(initial, maxBackoff time.Duration) RelayOpt {
+	return func(r *Relay) {
+		r.backoff = initial
+		r.maxBackoff = maxBackoff
+	}
+}
+
+// WithErrorHandler - функция, вызываемая при ошибке публикации события. Event.Attempts содержит количество
+// предыдущих неудачных попыток
+func WithErrorHandler(onError func(Event, error)) RelayOpt {
+	return func(r *Relay) {
+		r.onError = onError
+	}
+}
+
+// WithDeadLetterHandler - функция, вызываемая, когда событие перемещается в Outbox.DeadLetters после исчерпания
+// попыток публикации. err - ошибка последней попытки
+func WithDeadLetterHandler(onDead func(Event, error)) RelayOpt {
+	return func(r *Relay) {
+		r.onDead = onDead
+	}
+}
+
+// WithStoreErrorHandler - функция, вызываемая в Run при ошибке чтения или обновления таблицы событий
+func WithStoreErrorHandler(onStoreError func(error)) RelayOpt {
+	return func(r *Relay) {
+		r.onStoreError = onStoreError
+	}
+}
+
+// NewRelay возвращает новый Relay
+func NewRelay(outbox *Outbox, publisher Publisher, opts ...RelayOpt) *Relay {
+	r := &Relay{
+		outbox:       outbox,
+		publisher:    publisher,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		maxAttempts:  defaultMaxAttempts,
+		backoff:      defaultInitialBackoff,
+		maxBackoff:   defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.pollInterval <= 0 {
+		r.pollInterval = defaultPollInterval
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = defaultBatchSize
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = defaultMaxAttempts
+	}
+	if r.backoff <= 0 {
+		r.backoff = defaultInitialBackoff
+	}
+	if r.maxBackoff < r.backoff {
+		r.maxBackoff = max(r.backoff, defaultMaxBackoff)
+	}
+
+	return r
+}
+
+// Run публикует события до отмены ctx. Ошибки чтения и обновления таблицы событий не прерывают работу: Relay
+// повторит попытку через интервал опроса, а ошибка будет передана в WithStoreErrorHandler. Возвращает ctx.Err().
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		published, err := r.RelayOnce(ctx)
+		if err == nil && published == r.batchSize {
+			continue
+		}
+		if err != nil && ctx.Err() == nil && r.onStoreError != nil {
+			r.onStoreError(err)
+		}
+
+		timer := time.NewTimer(r.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RelayOnce публикует одну партию событий, готовых к публикации, и возвращает количество опубликованных.
+// Возвращаются только ошибки чтения и обновления таблицы событий; ошибки публикации передаются в WithErrorHandler.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.outbox.pending(ctx, time.Now(), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		publishErr := r.publisher.Publish(ctx, event)
+		if publishErr != nil {
+			if r.onError != nil {
+				r.onError(event, publishErr)
+			}
+
+			dead := event.Attempts+1 >= r.maxAttempts
+			err = r.outbox.markFailed(ctx, event.Id, publishErr, time.Now().Add(r.delay(event.Attempts)), dead)
+			if err != nil {
+				return published, err
+			}
+			if dead && r.onDead != nil {
+				event.Attempts++
+				event.LastError = publishErr.Error()
+				r.onDead(event, publishErr)
+			}
+			continue
+		}
+
+		if err = r.outbox.markPublished(ctx, event.Id); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// delay возвращает задержку перед следующей попыткой публикации события, у которого уже было attempts неудачных
+// попыток
+func (r *Relay) delay(attempts int) time.Duration {
+	delay := r.backoff
+	for range attempts {
+		if delay >= r.maxBackoff/2 {
+			return r.maxBackoff
+		}
+		delay *= 2
+	}
+	return min(delay, r.maxBackoff)
+}
@@ -1,5 +1,7 @@
 package oacquiring
 
+import "strconv"
+
 const (
 	// PaymentStatusInProgress - Платеж ожидает подтверждения, которое должно быть выполнено клиентом
 	// на мобильном устройстве.
@@ -34,3 +36,23 @@ const (
 	// Код: TECHNICAL_CANCELLING
 	PaymentStatusTechCancel = 5
 )
+
+// String возвращает код статуса, например IN_PROGRESS или OK
+func (s PaymentStatus) String() string {
+	switch s {
+	case PaymentStatusInProgress:
+		return "IN_PROGRESS"
+	case PaymentStatusDone:
+		return "OK"
+	case PaymentStatusDeclined:
+		return "DECLINE"
+	case PaymentStatusNotEnoughMoney:
+		return "NOT_ENOUGH"
+	case PaymentStatusTimeout:
+		return "TIMEOUT"
+	case PaymentStatusTechCancel:
+		return "TECHNICAL_CANCELLING"
+	default:
+		return "UNKNOWN(" + strconv.Itoa(int(s)) + ")"
+	}
+}
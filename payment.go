@@ -1,11 +1,8 @@
 package oacquiring
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 )
 
@@ -55,50 +52,24 @@ func (a *Client) CreatePayment(ctx context.Context, payment Payment) (Successful
 		return SuccessfulPayment{}, errors.New("at least one item should be specified in Items")
 	}
 
-	request := a.makePaymentRequest(payment)
-
-	body, err := json.Marshal(&request)
-	if err != nil {
-		return SuccessfulPayment{}, fmt.Errorf("request encoding failed: %w", err)
-	}
-
-	r, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseUrl+"/pos/webPayments/v2", bytes.NewReader(body))
+	creds, err := a.getCredentials(ctx)
 	if err != nil {
-		return SuccessfulPayment{}, fmt.Errorf("request initialization failed: %w", err)
+		return SuccessfulPayment{}, err
 	}
 
-	r.Header.Set("RegNum", a.cashboxRegNumber)
-	r.Header.Set("Password", a.cashboxPassword)
-	r.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.httpClient.Do(r)
-	if err != nil {
-		return SuccessfulPayment{}, fmt.Errorf("request execution failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		var errResp errorResponse
-		err = json.NewDecoder(resp.Body).Decode(&errResp)
-		if err != nil {
-			return SuccessfulPayment{}, fmt.Errorf("decoding error failed: %w (status code %d)", err, resp.StatusCode)
-		}
-		return SuccessfulPayment{}, &ServerError{
-			StatusCode:   errResp.Code,
-			InternalCode: errResp.InternalCode,
-			Message:      errResp.DevMessage,
-			UserMessage:  errResp.UserMessage.LangRu,
-		}
-	}
+	request := makePaymentRequest(creds.RegNum, payment)
 
 	var successfulPayment newPaymentResponse
-	err = json.NewDecoder(resp.Body).Decode(&successfulPayment)
+	err = a.do(ctx, creds, OperationCreatePayment, http.MethodPost, "/pos/webPayments/v2", &request, &successfulPayment)
 	if err != nil {
-		return SuccessfulPayment{}, fmt.Errorf("decoding response failed: %w", err)
+		return SuccessfulPayment{}, err
 	}
 
-	return SuccessfulPayment{
+	result := SuccessfulPayment{
 		PaymentId:   successfulPayment.PaymentId,
 		RedirectUrl: successfulPayment.RedirectUrl,
-	}, nil
+	}
+	a.paymentCreated(ctx, payment, result)
+
+	return result, nil
 }
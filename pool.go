@@ -0,0 +1,317 @@
+package oacquiring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrCashboxNotRegistered - касса с указанным ключом не зарегистрирована в ClientPool
+var ErrCashboxNotRegistered = errors.New("cashbox is not registered in pool")
+
+type (
+	// Cashbox - описание кассы в ClientPool
+	Cashbox struct {
+		Key         string              // Уникальный ключ кассы в пуле. Если не указан, используется регистрационный номер
+		Shop        string              // Магазин, к которому относится касса. Может быть пустым
+		RegNum      string              // Регистрационный номер кассы, например OPL000011111
+		Password    string              // Пароль для интернет-кассы
+		Credentials CredentialsProvider // Источник данных для авторизации. Если указан, RegNum и Password не используются
+	}
+
+	// ClientPool - набор Client для нескольких касс, использующих общий http.Client (и, соответственно, общий
+	// http.RoundTripper с пулом соединений). Позволяет выбирать Client по ключу кассы или по магазину и получать
+	// отчеты по смене сразу для нескольких касс. Безопасен для конкурентного использования. Для инициализации
+	// используйте NewClientPool.
+	ClientPool struct {
+		baseUrl string
+		opts    []ClientOpt
+
+		mu        sync.RWMutex
+		cashboxes map[string]*pooledClient
+		shops     map[string][]string
+		next      map[string]int
+	}
+
+	// CashboxPaymentHandler - интерфейс для обработки уведомлений о платежах касс из ClientPool. Аналог
+	// PaymentNotificationHandler, дополнительно получающий кассу, к которой относится платеж.
+	CashboxPaymentHandler interface {
+		HandleCashboxPayment(cashbox Cashbox, payment PaymentInfo) error
+	}
+
+	// CashboxNotificationReceiver - дополнительный интерфейс CashboxPaymentHandler, аналог NotificationReceiver. Если
+	// обработчик, переданный в ClientPool.NotificationHandler, реализует CashboxNotificationReceiver, вместо
+	// HandleCashboxPayment вызывается ReceiveCashboxNotification с полным уведомлением.
+	CashboxNotificationReceiver interface {
+		ReceiveCashboxNotification(ctx context.Context, cashbox Cashbox, notification Notification) error
+	}
+
+	// CashboxKeyFunc - функция, определяющая ключ кассы по HTTP уведомлению. Например CashboxKeyFromQuery
+	CashboxKeyFunc func(r *http.Request) string
+
+	// ShiftReport - платежи за смену по одной кассе
+	ShiftReport struct {
+		Cashbox  Cashbox       // Касса
+		Payments []PaymentInfo // Платежи кассы за смену
+		Err      error         // Ошибка получения платежей
+	}
+
+	pooledClient struct {
+		cashbox Cashbox
+		client  *Client
+	}
+
+	cashboxHandler struct {
+		cashbox Cashbox
+		handler CashboxPaymentHandler
+	}
+)
+
+// NewClientPool возвращает новый пустой ClientPool.
+//   - baseUrl - Базовый URL сервера Оплати
+//   - opts - Дополнительные настройки, применяемые ко всем кассам. Для общего транспорта используйте
+//     WithCustomHTTPClient
+func NewClientPool(baseUrl string, opts ...ClientOpt) *ClientPool {
+	return &ClientPool{
+		baseUrl:   baseUrl,
+		opts:      opts,
+		cashboxes: make(map[string]*pooledClient),
+		shops:     make(map[string][]string),
+		next:      make(map[string]int),
+	}
+}
+
+// Add регистрирует кассу в пуле. opts применяются после общих настроек пула. Если касса с таким ключом уже
+// зарегистрирована, возвращается ошибка.
+func (p *ClientPool) Add(cashbox Cashbox, opts ...ClientOpt) error {
+	if cashbox.Key == "" {
+		cashbox.Key = cashbox.RegNum
+	}
+	if cashbox.Key == "" {
+		return errors.New("cashbox Key or RegNum should be specified")
+	}
+
+	clientOpts := slices.Concat(p.opts, opts)
+	if cashbox.Credentials != nil {
+		clientOpts = append(clientOpts, WithCredentialsProvider(cashbox.Credentials))
+	}
+	client := NewClient(p.baseUrl, cashbox.RegNum, cashbox.Password, clientOpts...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.cashboxes[cashbox.Key]; ok {
+		return fmt.Errorf("cashbox %s is already registered", cashbox.Key)
+	}
+
+	p.cashboxes[cashbox.Key] = &pooledClient{cashbox: cashbox, client: &client}
+	if cashbox.Shop != "" {
+		p.shops[cashbox.Shop] = append(p.shops[cashbox.Shop], cashbox.Key)
+	}
+
+	return nil
+}
+
+// Remove удаляет кассу из пула
+func (p *ClientPool) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc, ok := p.cashboxes[key]
+	if !ok {
+		return
+	}
+	delete(p.cashboxes, key)
+
+	if shop := pc.cashbox.Shop; shop != "" {
+		p.shops[shop] = slices.DeleteFunc(p.shops[shop], func(k string) bool { return k == key })
+		if len(p.shops[shop]) == 0 {
+			delete(p.shops, shop)
+			delete(p.next, shop)
+		}
+	}
+}
+
+// Client возвращает Client кассы с ключом key. Если касса не зарегистрирована, возвращается ErrCashboxNotRegistered.
+func (p *ClientPool) Client(key string) (*Client, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	pc, ok := p.cashboxes[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCashboxNotRegistered, key)
+	}
+	return pc.client, nil
+}
+
+// ClientForShop возвращает Client одной из касс магазина shop. Если у магазина несколько касс, они выбираются по
+// очереди. Для операций с уже созданным платежом используйте Client с ключом кассы, создавшей платеж.
+func (p *ClientPool) ClientForShop(shop string) (Cashbox, *Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := p.shops[shop]
+	if len(keys) == 0 {
+		return Cashbox{}, nil, fmt.Errorf("%w: no cashboxes for shop %s", ErrCashboxNotRegistered, shop)
+	}
+
+	i := p.next[shop] % len(keys)
+	p.next[shop] = i + 1
+
+	pc := p.cashboxes[keys[i]]
+	return pc.cashbox, pc.client, nil
+}
+
+// Cashboxes возвращает список зарегистрированных касс, отсортированный по ключу
+func (p *ClientPool) Cashboxes() []Cashbox {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.cashboxesLocked()
+}
+
+// GetPaymentsOnShift выполняет Client.GetPaymentsOnShift параллельно для касс с ключами keys (или для всех касс, если
+// keys не указаны). Результат содержит ShiftReport по каждой кассе в порядке keys (или в порядке ключей). Ошибки
+// отдельных касс возвращаются в ShiftReport.Err, а также объединяются с помощью errors.Join в возвращаемый error.
+func (p *ClientPool) GetPaymentsOnShift(ctx context.Context, shift string, keys ...string) ([]ShiftReport, error) {
+	var clients []*pooledClient
+
+	p.mu.RLock()
+	if len(keys) == 0 {
+		for _, cashbox := range p.cashboxesLocked() {
+			clients = append(clients, p.cashboxes[cashbox.Key])
+		}
+	} else {
+		for _, key := range keys {
+			pc, ok := p.cashboxes[key]
+			if !ok {
+				p.mu.RUnlock()
+				return nil, fmt.Errorf("%w: %s", ErrCashboxNotRegistered, key)
+			}
+			clients = append(clients, pc)
+		}
+	}
+	p.mu.RUnlock()
+
+	reports := make([]ShiftReport, len(clients))
+
+	var wg sync.WaitGroup
+	for i, pc := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			payments, err := pc.client.GetPaymentsOnShift(ctx, shift)
+			if err != nil {
+				err = fmt.Errorf("cashbox %s: %w", pc.cashbox.Key, err)
+			}
+			reports[i] = ShiftReport{Cashbox: pc.cashbox, Payments: payments, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, report := range reports {
+		errs = append(errs, report.Err)
+	}
+
+	return reports, errors.Join(errs...)
+}
+
+func (p *ClientPool) cashboxesLocked() []Cashbox {
+	cashboxes := make([]Cashbox, 0, len(p.cashboxes))
+	for _, pc := range p.cashboxes {
+		cashboxes = append(cashboxes, pc.cashbox)
+	}
+	slices.SortFunc(cashboxes, func(a, b Cashbox) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return cashboxes
+}
+
+// NotificationHandler возвращает http.Handler для получения уведомлений по всем кассам пула. Касса определяется
+// функцией keyFunc, поэтому NotificationUrl платежа должен содержать ключ кассы, например
+// https://my.shop.by/api/webhook?cashbox=OPL000011111 для CashboxKeyFromQuery("cashbox"). Касса определяется после
+// проверки подписи, а для неизвестной кассы, как и для неверной подписи, возвращается "401 Unauthorized", поэтому по
+// ответам нельзя узнать, какие кассы есть в пуле. В остальном поведение совпадает с HTTPNotificationHandler: если handler
+// реализует CashboxNotificationReceiver или NotificationReceiver, он получает уведомление целиком (исходное тело,
+// заголовки и контекст запроса).
+func (p *ClientPool) NotificationHandler(publicKey string, handler CashboxPaymentHandler, keyFunc CashboxKeyFunc, opts ...NotificationHandlerOpt) (http.Handler, error) {
+	if handler == nil {
+		return nil, errors.New("nil handler is not allowed")
+	}
+	if keyFunc == nil {
+		return nil, errors.New("nil keyFunc is not allowed")
+	}
+
+	// Проверка ключа и опций выполняется один раз, при получении уведомления HTTPNotificationHandler копируется с
+	// обработчиком нужной кассы.
+	base, err := NewHTTPNotificationHandler(publicKey, PaymentNotificationHandlerFunc(func(PaymentInfo) error { return nil }), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		err = base.verify(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		p.mu.RLock()
+		pc, ok := p.cashboxes[keyFunc(r)]
+		p.mu.RUnlock()
+		if !ok {
+			http.Error(w, "unknown cashbox", http.StatusUnauthorized)
+			return
+		}
+
+		nh := base
+		nh.handler = &cashboxHandler{cashbox: pc.cashbox, handler: handler}
+		status, err := nh.handleVerified(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+		}
+	}), nil
+}
+
+func (h *cashboxHandler) HandlePayment(payment PaymentInfo) error {
+	return h.handler.HandleCashboxPayment(h.cashbox, payment)
+}
+
+// ReceiveNotification реализует NotificationReceiver
+func (h *cashboxHandler) ReceiveNotification(ctx context.Context, notification Notification) error {
+	switch handler := h.handler.(type) {
+	case CashboxNotificationReceiver:
+		return handler.ReceiveCashboxNotification(ctx, h.cashbox, notification)
+	case NotificationReceiver:
+		return handler.ReceiveNotification(ctx, notification)
+	default:
+		return h.handler.HandleCashboxPayment(h.cashbox, notification.Payment)
+	}
+}
+
+// CashboxKeyFromQuery возвращает CashboxKeyFunc, берущую ключ кассы из параметра запроса param
+func CashboxKeyFromQuery(param string) CashboxKeyFunc {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(param)
+	}
+}
+
+// CashboxKeyFromPath возвращает CashboxKeyFunc, берущую ключ кассы из параметра пути name шаблона http.ServeMux,
+// например для шаблона "POST /oplati/{cashbox}/notification" используйте CashboxKeyFromPath("cashbox")
+func CashboxKeyFromPath(name string) CashboxKeyFunc {
+	return func(r *http.Request) string {
+		return r.PathValue(name)
+	}
+}
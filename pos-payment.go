@@ -0,0 +1,97 @@
+package oacquiring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultPollInterval - интервал опроса статуса платежа в WaitPayment по умолчанию
+const defaultPollInterval = 2 * time.Second
+
+type (
+	// POSPayment - данные, необходимые для создания платежа на кассе магазина. Покупатель оплачивает его, сканируя
+	// динамический QR код приложением Оплати.
+	POSPayment struct {
+		Shift             string        // Смена. Например дата в формате ДДММГГГГ
+		OrderNumber       string        // Уникальный номер заказа
+		Items             []PaymentItem // Список позиций в чеке
+		ReceiptFooterText string        // Дополнительная информация в конце чека
+	}
+
+	// DynamicQRPayment - Результат успешного создания платежа на кассе магазина
+	DynamicQRPayment struct {
+		PaymentId int64         // Идентификатор платежа в Оплати
+		Status    PaymentStatus // Статус платежа, как правило PaymentStatusInProgress
+		QRData    string        // Данные динамического QR кода для отображения покупателю
+	}
+)
+
+// CreatePOSPayment - создание платежа на кассе магазина. Возвращает уникальный номер платежа в системе Оплати и данные
+// динамического QR кода, который необходимо показать покупателю (например, с помощью пакета qrcode). Статус платежа
+// можно получить с помощью GetPaymentInfo или дождаться окончательного статуса с помощью WaitPayment. Используется
+// запрос POST /pos/payments/v2.
+//
+// В случае, если сервер вернул ответ отличный от 200 OK, возвращаемый error можно попробовать привести к *ServerError
+// для получения дополнительных данных об ошибке.
+func (a *Client) CreatePOSPayment(ctx context.Context, payment POSPayment) (DynamicQRPayment, error) {
+	if len(payment.Items) == 0 {
+		return DynamicQRPayment{}, errors.New("at least one item should be specified in Items")
+	}
+
+	creds, err := a.getCredentials(ctx)
+	if err != nil {
+		return DynamicQRPayment{}, err
+	}
+
+	request := makePOSPaymentRequest(creds.RegNum, payment)
+
+	var rawPayment newPOSPaymentResponse
+	err = a.do(ctx, creds, OperationCreatePOSPayment, http.MethodPost, "/pos/payments/v2", &request, &rawPayment)
+	if err != nil {
+		return DynamicQRPayment{}, err
+	}
+
+	result := DynamicQRPayment{
+		PaymentId: rawPayment.PaymentId,
+		Status:    PaymentStatus(rawPayment.Status),
+		QRData:    rawPayment.DynamicQR,
+	}
+	a.paymentCreated(ctx, Payment{
+		Shift:             payment.Shift,
+		OrderNumber:       payment.OrderNumber,
+		Items:             payment.Items,
+		ReceiptFooterText: payment.ReceiptFooterText,
+	}, SuccessfulPayment{PaymentId: result.PaymentId})
+
+	return result, nil
+}
+
+// WaitPayment опрашивает статус платежа с помощью GetPaymentInfo с интервалом pollInterval (по умолчанию 2 секунды),
+// пока платеж находится в статусе PaymentStatusInProgress, и возвращает PaymentInfo с окончательным статусом.
+// Временные ошибки (сетевые и *ServerError с Retryable() == true) не прерывают ожидание. Для ограничения времени
+// ожидания используйте ctx, при его отмене возвращается последняя полученная ошибка либо ошибка ctx.
+func (a *Client) WaitPayment(ctx context.Context, paymentId int64, pollInterval time.Duration) (PaymentInfo, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	for {
+		paymentInfo, err := a.GetPaymentInfo(ctx, paymentId)
+		switch {
+		case err == nil && paymentInfo.Status != PaymentStatusInProgress:
+			return paymentInfo, nil
+		case err != nil && !isTransientError(err):
+			return PaymentInfo{}, err
+		}
+
+		if !sleep(ctx, pollInterval) {
+			if err != nil {
+				return PaymentInfo{}, err
+			}
+			return PaymentInfo{}, fmt.Errorf("waiting for payment %d failed: %w", paymentId, ctx.Err())
+		}
+	}
+}
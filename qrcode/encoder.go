@@ -0,0 +1,495 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+
+	// Штрафы за маску (ISO/IEC 18004, 7.8.3)
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+)
+
+// ErrTooLong - данные не помещаются в QR код версии 40 с выбранным уровнем коррекции ошибок
+var ErrTooLong = errors.New("data is too long for QR code")
+
+// encoder - построитель матрицы QR кода заданной версии
+type encoder struct {
+	version   int
+	size      int
+	modules   [][]bool
+	function  [][]bool
+	codewords [][]int16 // Индекс кодового слова модуля данных, -1 для остальных модулей
+}
+
+// encode кодирует data в байтовом режиме с минимально возможной версией для уровня level
+func encode(data []byte, level Level) (*Code, error) {
+	version, err := chooseVersion(len(data), level)
+	if err != nil {
+		return nil, err
+	}
+
+	codewords, blocks := addErrorCorrection(makeDataCodewords(data, version, level), version, level)
+
+	e := newEncoder(version)
+	e.drawFunctionPatterns()
+	e.drawCodewords(codewords)
+
+	bestMask, minPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		e.applyMask(mask)
+		e.drawFormatBits(level, mask)
+		penalty := e.penalty()
+		if minPenalty < 0 || penalty < minPenalty {
+			bestMask, minPenalty = mask, penalty
+		}
+		e.applyMask(mask) // XOR повторно отменяет маску
+	}
+
+	e.applyMask(bestMask)
+	e.drawFormatBits(level, bestMask)
+
+	return &Code{
+		Version:     version,
+		Level:       level,
+		Size:        e.size,
+		modules:     e.modules,
+		codewords:   e.codewords,
+		blocks:      blocks,
+		eccPerBlock: eccCodewordsPerBlock[level][version],
+	}, nil
+}
+
+// chooseVersion возвращает минимальную версию, вмещающую n байт в байтовом режиме
+func chooseVersion(n int, level Level) (int, error) {
+	for version := minVersion; version <= maxVersion; version++ {
+		if dataBits(n, version) <= numDataCodewords(version, level)*8 {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %d bytes with level %s", ErrTooLong, n, level)
+}
+
+// dataBits возвращает количество бит сегмента байтового режима длиной n
+func dataBits(n, version int) int {
+	return 4 + charCountBits(version) + n*8
+}
+
+// charCountBits возвращает длину поля количества символов для байтового режима
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// numRawDataModules возвращает количество модулей, доступных для данных и кодов коррекции, без функциональных
+// шаблонов
+func numRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// numDataCodewords возвращает количество кодовых слов данных для версии и уровня коррекции
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*numErrorCorrectionBlocks[level][version]
+}
+
+// makeDataCodewords формирует кодовые слова данных: сегмент байтового режима, терминатор и байты заполнения
+func makeDataCodewords(data []byte, version int, level Level) []byte {
+	capacity := numDataCodewords(version, level)
+
+	var bb bitBuffer
+	bb.append(0b0100, 4)
+	bb.append(uint32(len(data)), charCountBits(version))
+	for _, b := range data {
+		bb.append(uint32(b), 8)
+	}
+
+	bb.append(0, min(4, capacity*8-bb.len()))
+	bb.append(0, (8-bb.len()%8)%8)
+	for pad := uint32(0xEC); bb.len() < capacity*8; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	return bb.bytes()
+}
+
+// addErrorCorrection делит данные на блоки, добавляет к каждому коды Рида-Соломона и чередует блоки. Возвращает
+// кодовые слова и номер блока каждого из них.
+func addErrorCorrection(data []byte, version int, level Level) ([]byte, []int) {
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	blockEccLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := reedSolomonDivisor(blockEccLen)
+
+	blocks := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		datLen := shortBlockLen - blockEccLen
+		if i >= numShortBlocks {
+			datLen++
+		}
+
+		block := make([]byte, 0, shortBlockLen+1)
+		block = append(block, data[k:k+datLen]...)
+		k += datLen
+
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	blockOf := make([]int, 0, rawCodewords)
+	for i := 0; i <= shortBlockLen; i++ {
+		for j, block := range blocks {
+			// Пропуск байта-заполнителя коротких блоков
+			if i != shortBlockLen-blockEccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+				blockOf = append(blockOf, j)
+			}
+		}
+	}
+
+	return result, blockOf
+}
+
+func newEncoder(version int) *encoder {
+	size := version*4 + 17
+
+	e := &encoder{
+		version:   version,
+		size:      size,
+		modules:   make([][]bool, size),
+		function:  make([][]bool, size),
+		codewords: make([][]int16, size),
+	}
+	for i := range size {
+		e.modules[i] = make([]bool, size)
+		e.function[i] = make([]bool, size)
+		e.codewords[i] = make([]int16, size)
+		for j := range size {
+			e.codewords[i][j] = -1
+		}
+	}
+
+	return e
+}
+
+func (e *encoder) setFunction(x, y int, dark bool) {
+	e.modules[y][x] = dark
+	e.function[y][x] = true
+}
+
+func (e *encoder) drawFunctionPatterns() {
+	for i := range e.size {
+		e.setFunction(6, i, i%2 == 0)
+		e.setFunction(i, 6, i%2 == 0)
+	}
+
+	e.drawFinderPattern(3, 3)
+	e.drawFinderPattern(e.size-4, 3)
+	e.drawFinderPattern(3, e.size-4)
+
+	positions := e.alignmentPatternPositions()
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			e.drawAlignmentPattern(x, y)
+		}
+	}
+
+	// Резервирование области формата, заполняется в drawFormatBits
+	e.drawFormatBits(0, 0)
+	e.drawVersionBits()
+}
+
+func (e *encoder) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			dist := max(abs(dx), abs(dy))
+			xx, yy := x+dx, y+dy
+			if xx >= 0 && xx < e.size && yy >= 0 && yy < e.size {
+				e.setFunction(xx, yy, dist != 2 && dist != 4)
+			}
+		}
+	}
+}
+
+func (e *encoder) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			e.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPatternPositions возвращает координаты центров выравнивающих шаблонов по одной оси
+func (e *encoder) alignmentPatternPositions() []int {
+	if e.version == 1 {
+		return nil
+	}
+
+	numAlign := e.version/7 + 2
+	step := (e.version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+
+	result := make([]int, numAlign)
+	result[0] = 6
+	for i, pos := numAlign-1, e.size-7; i >= 1; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+
+	return result
+}
+
+// formatInformation возвращает 15 бит информации о формате: уровень коррекции и маска, код БЧХ (15,5) и маска 0x5412
+// (ISO/IEC 18004, 7.9.1)
+func formatInformation(level Level, mask int) int {
+	data := level.formatBits()<<3 | mask
+	rem := data
+	for range 10 {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionInformation возвращает 18 бит информации о версии: версия и код БЧХ (18,6) (ISO/IEC 18004, 7.10)
+func versionInformation(version int) int {
+	rem := version
+	for range 12 {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+func (e *encoder) drawFormatBits(level Level, mask int) {
+	bits := formatInformation(level, mask)
+
+	for i := 0; i <= 5; i++ {
+		e.setFunction(8, i, bit(bits, i))
+	}
+	e.setFunction(8, 7, bit(bits, 6))
+	e.setFunction(8, 8, bit(bits, 7))
+	e.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		e.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		e.setFunction(e.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		e.setFunction(8, e.size-15+i, bit(bits, i))
+	}
+	e.setFunction(8, e.size-8, true)
+}
+
+func (e *encoder) drawVersionBits() {
+	if e.version < 7 {
+		return
+	}
+
+	bits := versionInformation(e.version)
+
+	for i := 0; i < 18; i++ {
+		dark := bit(bits, i)
+		a, b := e.size-11+i%3, i/3
+		e.setFunction(a, b, dark)
+		e.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords размещает кодовые слова зигзагом по столбцам из двух модулей
+func (e *encoder) drawCodewords(data []byte) {
+	i := 0
+	for right := e.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < e.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = e.size - 1 - vert
+				}
+				if !e.function[y][x] && i < len(data)*8 {
+					e.modules[y][x] = bit(int(data[i>>3]), 7-(i&7))
+					e.codewords[y][x] = int16(i >> 3)
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask инвертирует модули данных по шаблону маски. Повторное применение отменяет маску.
+func (e *encoder) applyMask(mask int) {
+	for y := 0; y < e.size; y++ {
+		for x := 0; x < e.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !e.function[y][x] {
+				e.modules[y][x] = !e.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty вычисляет штраф матрицы для выбора маски
+func (e *encoder) penalty() int {
+	result := 0
+	dark := 0
+
+	for y := 0; y < e.size; y++ {
+		result += runPenalty(func(i int) bool { return e.modules[y][i] }, e.size)
+		result += finderLikePenalty(func(i int) bool { return e.modules[y][i] }, e.size)
+	}
+	for x := 0; x < e.size; x++ {
+		result += runPenalty(func(i int) bool { return e.modules[i][x] }, e.size)
+		result += finderLikePenalty(func(i int) bool { return e.modules[i][x] }, e.size)
+	}
+
+	for y := 0; y < e.size; y++ {
+		for x := 0; x < e.size; x++ {
+			if e.modules[y][x] {
+				dark++
+			}
+			if x < e.size-1 && y < e.size-1 {
+				c := e.modules[y][x]
+				if c == e.modules[y][x+1] && c == e.modules[y+1][x] && c == e.modules[y+1][x+1] {
+					result += penaltyN2
+				}
+			}
+		}
+	}
+
+	total := e.size * e.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * penaltyN4
+
+	return result
+}
+
+// runPenalty - штраф за последовательности из 5 и более модулей одного цвета
+func runPenalty(at func(int) bool, n int) int {
+	result := 0
+	run := 1
+	for i := 1; i <= n; i++ {
+		if i < n && at(i) == at(i-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			result += penaltyN1 + run - 5
+		}
+		run = 1
+	}
+	return result
+}
+
+// finderLikePenalty - штраф за последовательности 1:1:3:1:1, похожие на поисковые шаблоны, со светлой областью из
+// 4 модулей с одной из сторон
+func finderLikePenalty(at func(int) bool, n int) int {
+	pattern := [7]bool{true, false, true, true, true, false, true}
+	light := func(from, to int) bool {
+		for i := max(from, 0); i < min(to, n); i++ {
+			if at(i) {
+				return false
+			}
+		}
+		return true
+	}
+
+	result := 0
+	for i := 0; i+7 <= n; i++ {
+		match := true
+		for j, dark := range pattern {
+			if at(i+j) != dark {
+				match = false
+				break
+			}
+		}
+		if match && (light(i-4, i) || light(i+7, i+11)) {
+			result += penaltyN3
+		}
+	}
+	return result
+}
+
+func bit(x, i int) bool {
+	return (x>>i)&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// bitBuffer - последовательность бит
+type bitBuffer struct {
+	data []byte
+	n    int
+}
+
+func (b *bitBuffer) len() int {
+	return b.n
+}
+
+// append добавляет n младших бит value, начиная со старшего
+func (b *bitBuffer) append(value uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		if (value>>i)&1 != 0 {
+			b.data[b.n/8] |= 0x80 >> (b.n % 8)
+		}
+		b.n++
+	}
+}
+
+func (b *bitBuffer) bytes() []byte {
+	return b.data
+}
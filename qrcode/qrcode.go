@@ -0,0 +1,101 @@
+// Package qrcode содержит генератор QR кодов для ссылок на оплату, например SuccessfulPayment.RedirectUrl, который
+// можно отсканировать приложением Оплати. Генерация выполняется локально, без обращения к внешним сервисам.
+//
+//	result, err := oplatiClient.CreatePayment(ctx, paymentData)
+//	// ...
+//	code, err := qrcode.ForPayment(result, qrcode.LevelM)
+//	// ...
+//	png, err := code.PNG(qrcode.Options{Size: 512})
+//	svg := code.SVG(qrcode.Options{})
+//	fmt.Print(code.Terminal())
+//
+// Для платежей на кассе магазина используйте ForPOSPayment.
+package qrcode
+
+import (
+	"fmt"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+const (
+	// LevelL - восстанавливается до 7% поврежденных данных
+	LevelL Level = iota
+	// LevelM - восстанавливается до 15% поврежденных данных
+	LevelM
+	// LevelQ - восстанавливается до 25% поврежденных данных
+	LevelQ
+	// LevelH - восстанавливается до 30% поврежденных данных. Рекомендуется при использовании Options.Logo
+	LevelH
+)
+
+type (
+	// Level - уровень коррекции ошибок QR кода. Варианты: LevelL, LevelM, LevelQ, LevelH
+	Level int
+
+	// Code - QR код. Для создания используйте Encode или ForPayment
+	Code struct {
+		Version int   // Версия QR кода, 1-40
+		Level   Level // Уровень коррекции ошибок
+		Size    int   // Количество модулей по одной стороне, без отступа
+
+		modules     [][]bool
+		codewords   [][]int16 // Индекс кодового слова модуля данных, -1 для остальных модулей
+		blocks      []int     // Номер блока коррекции ошибок каждого кодового слова
+		eccPerBlock int       // Количество кодовых слов коррекции ошибок в блоке
+	}
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelL:
+		return "L"
+	case LevelM:
+		return "M"
+	case LevelQ:
+		return "Q"
+	case LevelH:
+		return "H"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// formatBits возвращает код уровня коррекции в информации о формате
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// Encode кодирует content (в байтовом режиме, UTF-8) в QR код минимальной версии для уровня коррекции level. Если
+// content не помещается в QR код версии 40, возвращается ErrTooLong.
+func Encode(content string, level Level) (*Code, error) {
+	if level < LevelL || level > LevelH {
+		return nil, fmt.Errorf("unknown error correction level %d", int(level))
+	}
+	return encode([]byte(content), level)
+}
+
+// ForPayment кодирует SuccessfulPayment.RedirectUrl в QR код
+func ForPayment(payment oacquiring.SuccessfulPayment, level Level) (*Code, error) {
+	if payment.RedirectUrl == "" {
+		return nil, fmt.Errorf("payment %d has empty RedirectUrl", payment.PaymentId)
+	}
+	return Encode(payment.RedirectUrl, level)
+}
+
+// Dark возвращает true, если модуль с координатами x, y (от левого верхнего угла) темный. Для координат вне кода
+// возвращает false.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// ForPOSPayment кодирует данные динамического QR кода DynamicQRPayment.QRData в QR код
+func ForPOSPayment(payment oacquiring.DynamicQRPayment, level Level) (*Code, error) {
+	if payment.QRData == "" {
+		return nil, fmt.Errorf("payment %d has empty QRData", payment.PaymentId)
+	}
+	return Encode(payment.QRData, level)
+}
@@ -0,0 +1,439 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Известные значения из ISO/IEC 18004. Декодер ниже использует только их и описание стандарта, а не код кодировщика,
+// поэтому ошибка в кодировщике не может быть повторена в проверке.
+var (
+	// formatInformationTable - информация о формате по уровню коррекции и маске (приложение C, таблица C.1)
+	formatInformationTable = [4][8]int{
+		LevelL: {0x77C4, 0x72F3, 0x7DAA, 0x789D, 0x662F, 0x6318, 0x6C41, 0x6976},
+		LevelM: {0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0},
+		LevelQ: {0x355F, 0x3068, 0x3F31, 0x3A06, 0x24B4, 0x2183, 0x2EDA, 0x2BED},
+		LevelH: {0x1689, 0x13BE, 0x1CE7, 0x19D0, 0x0762, 0x0255, 0x0D0C, 0x083B},
+	}
+
+	// versionInformationTable - информация о версии для версий 7-40 (приложение D, таблица D.1)
+	versionInformationTable = map[int]int{
+		7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3, 11: 0x0BBF6, 12: 0x0C762, 13: 0x0D847, 14: 0x0E60D,
+		15: 0x0F928, 16: 0x10B78, 17: 0x1145D, 18: 0x12A17, 19: 0x13532, 20: 0x149A6, 21: 0x15683, 22: 0x168C9,
+		23: 0x177EC, 24: 0x18EC4, 25: 0x191E1, 26: 0x1AFAB, 27: 0x1B08E, 28: 0x1CC1A, 29: 0x1D33F, 30: 0x1ED75,
+		31: 0x1F250, 32: 0x209D5, 33: 0x216F0, 34: 0x228BA, 35: 0x2379F, 36: 0x24B0B, 37: 0x2542E, 38: 0x26A64,
+		39: 0x27541, 40: 0x28C69,
+	}
+
+	// alignmentPatternTable - координаты центров выравнивающих узоров для проверяемых версий (приложение E, таблица
+	// E.1)
+	alignmentPatternTable = map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		6:  {6, 34},
+		7:  {6, 22, 38},
+		10: {6, 28, 50},
+		14: {6, 26, 46, 66},
+		25: {6, 32, 58, 84, 110},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	}
+)
+
+func TestReedSolomonAnnexExample(t *testing.T) {
+	// Пример кодирования "01234567" версией 1-M (приложение I): 16 кодовых слов данных и 10 слов коррекции ошибок
+	data := []byte{
+		0b00010000, 0b00100000, 0b00001100, 0b01010110, 0b01100001, 0b10000000, 0b11101100, 0b00010001,
+		0b11101100, 0b00010001, 0b11101100, 0b00010001, 0b11101100, 0b00010001, 0b11101100, 0b00010001,
+	}
+	want := []byte{
+		0b10100101, 0b00100100, 0b11010100, 0b11000001, 0b11101101,
+		0b00110110, 0b11000111, 0b10000111, 0b00101100, 0b01010101,
+	}
+
+	if eccCodewordsPerBlock[LevelM][1] != len(want) || numDataCodewords(1, LevelM) != len(data) {
+		t.Fatalf("version 1-M: %d data and %d error correction codewords, want %d and %d",
+			numDataCodewords(1, LevelM), eccCodewordsPerBlock[LevelM][1], len(data), len(want))
+	}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(len(want)))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("reedSolomonRemainder() = %x, want %x", got, want)
+	}
+}
+
+func TestFormatInformation(t *testing.T) {
+	for level := LevelL; level <= LevelH; level++ {
+		for mask := range 8 {
+			if got, want := formatInformation(level, mask), formatInformationTable[level][mask]; got != want {
+				t.Errorf("formatInformation(%s, %d) = %#04x, want %#04x", level, mask, got, want)
+			}
+		}
+	}
+}
+
+func TestVersionInformation(t *testing.T) {
+	for version, want := range versionInformationTable {
+		if got := versionInformation(version); got != want {
+			t.Errorf("versionInformation(%d) = %#05x, want %#05x", version, got, want)
+		}
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	for _, level := range []Level{LevelL, LevelM, LevelQ, LevelH} {
+		for version := range alignmentPatternTable {
+			// Наибольшее содержимое, помещающееся в версию, с неповторяющимися байтами
+			n := (numDataCodewords(version, level)*8 - 4 - charCountBits(version)) / 8
+			content := strings.Repeat("https://oplati.by/?p=0123456789", n/31+1)[:n]
+
+			t.Run(fmt.Sprintf("%d-%s", version, level), func(t *testing.T) {
+				code, err := Encode(content, level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if code.Version != version {
+					t.Fatalf("Version = %d, want %d", code.Version, version)
+				}
+
+				opts := Options{ModuleSize: 3}
+				modules := sampleModules(t, code.Image(opts), code.Size, opts)
+				got, gotLevel, err := decode(modules)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != content || gotLevel != level {
+					t.Fatalf("decoded %q with level %s, want %q with level %s", got, gotLevel, content, level)
+				}
+			})
+		}
+	}
+}
+
+func TestLogoWithinCorrection(t *testing.T) {
+	// Темный логотип с прозрачной полосой, чтобы под подложкой были и темные, и светлые модули
+	logo := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := range 48 {
+		for x := range 64 {
+			if y%16 < 12 {
+				logo.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	for _, level := range []Level{LevelL, LevelM, LevelQ, LevelH} {
+		for _, content := range []string{"https://oplati.by/p/1", strings.Repeat("x", 300)} {
+			code, err := Encode(content, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, scale := range []float64{0, maxLogoScale} {
+				t.Run(fmt.Sprintf("%d-%s-%v", code.Version, level, scale), func(t *testing.T) {
+					opts := Options{ModuleSize: 4, Logo: logo, LogoScale: scale}
+					damaged := damagedModules(code.Image(Options{ModuleSize: 4}), code.Image(opts), code.Size, opts)
+					if level == LevelH && len(damaged) == 0 {
+						t.Fatal("logo is not drawn with LevelH")
+					}
+
+					// Кодовые слова под логотипом в каждом блоке не должны превышать (ecc-p)/2, где p <= 3
+					// (раздел 7.5.1, таблица 9)
+					positions, blockOf := codewordLayout(code.Version, level)
+					budget := (eccCodewordsPerBlock[level][code.Version] - 3) / 2
+					counted := make(map[int]bool)
+					perBlock := make(map[int]int)
+					for _, p := range damaged {
+						cb, ok := positions[p]
+						if !ok || counted[cb.codeword] {
+							continue
+						}
+						counted[cb.codeword] = true
+						perBlock[blockOf[cb.codeword]]++
+					}
+					for block, n := range perBlock {
+						if n > budget {
+							t.Errorf("block %d has %d damaged codewords, error correction budget is %d", block, n, budget)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// sampleModules считывает модули из изображения кода по центрам модулей
+func sampleModules(t *testing.T, img image.Image, size int, opts Options) [][]bool {
+	t.Helper()
+
+	opts = opts.withDefaults(size)
+	modules := make([][]bool, size)
+	for y := range size {
+		modules[y] = make([]bool, size)
+		for x := range size {
+			px := (x+opts.QuietZone)*opts.ModuleSize + opts.ModuleSize/2
+			py := (y+opts.QuietZone)*opts.ModuleSize + opts.ModuleSize/2
+			r, _, _, _ := img.At(px, py).RGBA()
+			modules[y][x] = r < 0x8000
+		}
+	}
+	return modules
+}
+
+// damagedModules возвращает модули, в которых хотя бы один пиксель изображения with отличается от without
+func damagedModules(without, with image.Image, size int, opts Options) []image.Point {
+	opts = opts.withDefaults(size)
+	m := opts.ModuleSize
+
+	var damaged []image.Point
+	for y := range size {
+		for x := range size {
+			px, py := (x+opts.QuietZone)*m, (y+opts.QuietZone)*m
+		pixels:
+			for dy := range m {
+				for dx := range m {
+					if without.At(px+dx, py+dy) != with.At(px+dx, py+dy) {
+						damaged = append(damaged, image.Pt(x, y))
+						break pixels
+					}
+				}
+			}
+		}
+	}
+	return damaged
+}
+
+// decode декодирует QR код байтового режима по матрице модулей без исправления ошибок: проверяет, что синдромы всех
+// блоков равны нулю
+func decode(modules [][]bool) (string, Level, error) {
+	size := len(modules)
+	version := (size - 17) / 4
+
+	// Первая копия информации о формате (раздел 7.9.1, рисунок 25)
+	var format int
+	formatPositions := []image.Point{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8}, {7, 8},
+		{5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}}
+	for i, p := range formatPositions {
+		if modules[p.Y][p.X] {
+			format |= 1 << i
+		}
+	}
+
+	level, mask := Level(-1), -1
+	for l := range formatInformationTable {
+		if i := slices.Index(formatInformationTable[l][:], format); i >= 0 {
+			level, mask = Level(l), i
+		}
+	}
+	if mask < 0 {
+		return "", 0, fmt.Errorf("unknown format information %#04x", format)
+	}
+
+	positions, blockOf := codewordLayout(version, level)
+	raw := make([]byte, len(blockOf))
+	for p, cb := range positions {
+		if modules[p.Y][p.X] != maskCondition(mask, p.X, p.Y) {
+			raw[cb.codeword] |= 0x80 >> cb.bit
+		}
+	}
+
+	// Разделение чередующихся кодовых слов на блоки (раздел 7.6)
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	ecc := eccCodewordsPerBlock[level][version]
+	blocks := make([][]byte, numBlocks)
+	for i, b := range raw {
+		blocks[blockOf[i]] = append(blocks[blockOf[i]], b)
+	}
+
+	var data []byte
+	for i, block := range blocks {
+		if !zeroSyndromes(block, ecc) {
+			return "", 0, fmt.Errorf("block %d has errors", i)
+		}
+		data = append(data, block[:len(block)-ecc]...)
+	}
+
+	// Сегмент байтового режима (раздел 7.4.5)
+	r := bitReader{data: data}
+	if m := r.read(4); m != 0b0100 {
+		return "", 0, fmt.Errorf("unexpected mode %04b", m)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	n := r.read(countBits)
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(r.read(8))
+	}
+	if r.pos > len(data)*8 {
+		return "", 0, fmt.Errorf("segment length %d exceeds data capacity", n)
+	}
+
+	return string(content), level, nil
+}
+
+// codewordBit - бит bit (от старшего) кодового слова codeword
+type codewordBit struct {
+	codeword int
+	bit      int
+}
+
+// codewordLayout возвращает соответствие модулей данных битам кодовых слов (раздел 7.7.3) и номер блока каждого
+// кодового слова в порядке чередования (раздел 7.6)
+func codewordLayout(version int, level Level) (map[image.Point]codewordBit, []int) {
+	size := version*4 + 17
+
+	positions := make(map[image.Point]codewordBit)
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		upward := ((size-1-right)/2)%2 == 0
+		if right < 6 {
+			upward = ((size-2-right)/2)%2 == 0
+		}
+		for vert := range size {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for _, x := range []int{right, right - 1} {
+				if !isFunctionModule(version, x, y) {
+					positions[image.Pt(x, y)] = codewordBit{codeword: i / 8, bit: i % 8}
+					i++
+				}
+			}
+		}
+	}
+
+	total := i / 8
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	ecc := eccCodewordsPerBlock[level][version]
+	numShort := numBlocks - total%numBlocks
+	shortData := total/numBlocks - ecc
+
+	blockOf := make([]int, 0, total)
+	for j := 0; j <= shortData; j++ {
+		for b := range numBlocks {
+			if j < shortData || b >= numShort {
+				blockOf = append(blockOf, b)
+			}
+		}
+	}
+	for range ecc {
+		for b := range numBlocks {
+			blockOf = append(blockOf, b)
+		}
+	}
+
+	for p, cb := range positions {
+		if cb.codeword >= total {
+			delete(positions, p) // Остаточные биты
+		}
+	}
+
+	return positions, blockOf
+}
+
+// isFunctionModule сообщает, относится ли модуль к функциональным шаблонам, информации о формате или версии
+func isFunctionModule(version, x, y int) bool {
+	size := version*4 + 17
+	switch {
+	case x < 9 && y < 9, x >= size-8 && y < 9, x < 9 && y >= size-8: // Поисковые узоры, разделители и формат
+		return true
+	case x == 6 || y == 6: // Синхронизирующие полосы
+		return true
+	case version >= 7 && (x >= size-11 && x < size-8 && y < 6 || y >= size-11 && y < size-8 && x < 6):
+		return true
+	}
+
+	centers := alignmentPatternTable[version]
+	for _, cy := range centers {
+		for _, cx := range centers {
+			if cx == 6 && cy == 6 || cx == 6 && cy == size-7 || cx == size-7 && cy == 6 {
+				continue
+			}
+			if abs(x-cx) <= 2 && abs(y-cy) <= 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskCondition возвращает true, если модуль инвертируется маской mask (раздел 7.8.2, таблица 10)
+func maskCondition(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (y+x)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (y+x)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return (y*x)%2+(y*x)%3 == 0
+	case 6:
+		return ((y*x)%2+(y*x)%3)%2 == 0
+	default:
+		return ((y+x)%2+(y*x)%3)%2 == 0
+	}
+}
+
+// zeroSyndromes сообщает, делится ли блок на порождающий многочлен с корнями α^0..α^(ecc-1), т.е. не содержит ошибок
+func zeroSyndromes(block []byte, ecc int) bool {
+	var exp [255]byte
+	exp[0] = 1
+	for i := 1; i < 255; i++ {
+		v := int(exp[i-1]) << 1
+		if v >= 0x100 {
+			v ^= 0x11D
+		}
+		exp[i] = byte(v)
+	}
+	mul := func(a byte, i int) byte { // a * α^i
+		if a == 0 {
+			return 0
+		}
+		log := slices.Index(exp[:], a)
+		return exp[(log+i)%255]
+	}
+
+	for i := range ecc {
+		var s byte
+		for _, c := range block {
+			s = mul(s, i) ^ c
+		}
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bitReader читает биты от старшего к младшему
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	var v int
+	for range n {
+		v <<= 1
+		if r.pos < len(r.data)*8 && r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0 {
+			v |= 1
+		}
+		r.pos++
+	}
+	return v
+}
@@ -0,0 +1,321 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+)
+
+const (
+	defaultQuietZone = 4
+	defaultLogoScale = 0.2
+	maxLogoScale     = 0.3
+)
+
+type (
+	// Options - параметры отображения Code
+	Options struct {
+		// Size - желаемая ширина изображения в пикселях. Размер модуля округляется вниз, но не меньше 1 пикселя,
+		// поэтому итоговое изображение может быть меньше. Если 0, используется ModuleSize
+		Size int
+		// ModuleSize - размер модуля в пикселях, если Size не указан. По умолчанию 8
+		ModuleSize int
+		// QuietZone - ширина светлого отступа вокруг кода в модулях. По умолчанию 4, отрицательное значение - без отступа
+		QuietZone int
+		// Foreground - цвет темных модулей. По умолчанию черный
+		Foreground color.Color
+		// Background - цвет светлых модулей. По умолчанию белый
+		Background color.Color
+		// Logo - изображение, размещаемое в центре кода на светлой подложке. Логотип закрывает часть модулей, поэтому
+		// рекомендуется LevelH
+		Logo image.Image
+		// LogoScale - ширина логотипа относительно ширины кода, от 0 до 0.3. По умолчанию 0.2. Логотип уменьшается,
+		// если закрытые им кодовые слова не могут быть восстановлены при уровне коррекции кода, поэтому при LevelL и
+		// LevelM он может быть заметно меньше или не выводиться совсем
+		LogoScale float64
+	}
+)
+
+func (o Options) withDefaults(modules int) Options {
+	if o.QuietZone == 0 {
+		o.QuietZone = defaultQuietZone
+	}
+	if o.QuietZone < 0 {
+		o.QuietZone = 0
+	}
+	if o.Size > 0 {
+		o.ModuleSize = max(1, o.Size/(modules+2*o.QuietZone))
+	}
+	if o.ModuleSize <= 0 {
+		o.ModuleSize = 8
+	}
+	if o.Foreground == nil {
+		o.Foreground = color.Black
+	}
+	if o.Background == nil {
+		o.Background = color.White
+	}
+	if o.LogoScale <= 0 {
+		o.LogoScale = defaultLogoScale
+	}
+	o.LogoScale = min(o.LogoScale, maxLogoScale)
+	return o
+}
+
+// Image возвращает изображение QR кода
+func (c *Code) Image(opts Options) image.Image {
+	opts = opts.withDefaults(c.Size)
+
+	width := (c.Size + 2*opts.QuietZone) * opts.ModuleSize
+	img := image.NewRGBA(image.Rect(0, 0, width, width))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(opts.Foreground)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			px := (x + opts.QuietZone) * opts.ModuleSize
+			py := (y + opts.QuietZone) * opts.ModuleSize
+			draw.Draw(img, image.Rect(px, py, px+opts.ModuleSize, py+opts.ModuleSize), fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if opts.Logo != nil {
+		c.drawLogo(img, opts)
+	}
+
+	return img
+}
+
+// logoRect возвращает область логотипа в пикселях и область подложки, выровненную по модулям. Логотип уменьшается,
+// пока закрытые подложкой кодовые слова не уложатся в возможности коррекции ошибок (см. fitsCorrection).
+func (c *Code) logoRect(opts Options, logoBounds image.Rectangle) (logo, plate image.Rectangle) {
+	codeWidth := c.Size * opts.ModuleSize
+	for maxWidth := int(float64(codeWidth) * opts.LogoScale); maxWidth > 0; maxWidth -= opts.ModuleSize {
+		logo, plate = c.scaleLogo(opts, logoBounds, maxWidth)
+		if logo.Empty() || c.fitsCorrection(opts, plate) {
+			return logo, plate
+		}
+	}
+	return image.Rectangle{}, image.Rectangle{}
+}
+
+// scaleLogo возвращает область логотипа шириной не более maxWidth пикселей и его подложки
+func (c *Code) scaleLogo(opts Options, logoBounds image.Rectangle, maxWidth int) (logo, plate image.Rectangle) {
+	codeWidth := c.Size * opts.ModuleSize
+	offset := opts.QuietZone * opts.ModuleSize
+
+	w, h := logoBounds.Dx(), logoBounds.Dy()
+	if w == 0 || h == 0 || maxWidth == 0 {
+		return image.Rectangle{}, image.Rectangle{}
+	}
+	if w >= h {
+		w, h = maxWidth, max(1, h*maxWidth/w)
+	} else {
+		w, h = max(1, w*maxWidth/h), maxWidth
+	}
+
+	center := offset + codeWidth/2
+	logo = image.Rect(center-w/2, center-h/2, center-w/2+w, center-h/2+h)
+
+	// Подложка шире логотипа на один модуль с каждой стороны и выровнена по сетке модулей
+	m := opts.ModuleSize
+	plate = image.Rect(
+		(logo.Min.X/m-1)*m, (logo.Min.Y/m-1)*m,
+		((logo.Max.X+m-1)/m+1)*m, ((logo.Max.Y+m-1)/m+1)*m,
+	)
+
+	return logo, plate
+}
+
+// fitsCorrection сообщает, восстановимы ли данные кода, если модули под подложкой plate прочитаны неверно: в каждом
+// блоке количество затронутых кодовых слов не должно превышать (ecc-3)/2, где ecc - количество кодовых слов коррекции
+// ошибок в блоке, а 3 - наибольшее количество слов, резервируемых для защиты от ложного декодирования (ISO/IEC
+// 18004, таблица 9)
+func (c *Code) fitsCorrection(opts Options, plate image.Rectangle) bool {
+	if c.codewords == nil {
+		return false
+	}
+
+	m := opts.ModuleSize
+	offset := opts.QuietZone * m
+	minX, minY := max(0, (plate.Min.X-offset)/m), max(0, (plate.Min.Y-offset)/m)
+	maxX, maxY := min(c.Size, (plate.Max.X-offset)/m), min(c.Size, (plate.Max.Y-offset)/m)
+
+	budget := (c.eccPerBlock - 3) / 2
+	touched := make(map[int16]struct{})
+	damaged := make(map[int]int)
+	for y := minY; y < maxY; y++ {
+		for x := minX; x < maxX; x++ {
+			codeword := c.codewords[y][x]
+			if codeword < 0 {
+				continue
+			}
+			if _, ok := touched[codeword]; ok {
+				continue
+			}
+			touched[codeword] = struct{}{}
+
+			block := c.blocks[codeword]
+			damaged[block]++
+			if damaged[block] > budget {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (c *Code) drawLogo(img draw.Image, opts Options) {
+	logo, plate := c.logoRect(opts, opts.Logo.Bounds())
+	if logo.Empty() {
+		return
+	}
+
+	draw.Draw(img, plate, image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	// Масштабирование методом ближайшего соседа
+	src := opts.Logo.Bounds()
+	scaled := image.NewRGBA(logo)
+	for y := logo.Min.Y; y < logo.Max.Y; y++ {
+		sy := src.Min.Y + (y-logo.Min.Y)*src.Dy()/logo.Dy()
+		for x := logo.Min.X; x < logo.Max.X; x++ {
+			sx := src.Min.X + (x-logo.Min.X)*src.Dx()/logo.Dx()
+			scaled.Set(x, y, opts.Logo.At(sx, sy))
+		}
+	}
+	draw.Draw(img, logo, scaled, logo.Min, draw.Over)
+}
+
+// WritePNG записывает изображение QR кода в формате PNG
+func (c *Code) WritePNG(w io.Writer, opts Options) error {
+	err := png.Encode(w, c.Image(opts))
+	if err != nil {
+		return fmt.Errorf("png encoding failed: %w", err)
+	}
+	return nil
+}
+
+// PNG возвращает изображение QR кода в формате PNG
+func (c *Code) PNG(opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	err := c.WritePNG(&buf, opts)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG возвращает изображение QR кода в формате SVG. Модуль имеет размер 1 единицу viewBox, ширина и высота
+// изображения задаются Options.Size (или Options.ModuleSize). Логотип встраивается в формате PNG.
+func (c *Code) SVG(opts Options) []byte {
+	opts = opts.withDefaults(c.Size)
+
+	n := c.Size + 2*opts.QuietZone
+	width := n * opts.ModuleSize
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, width, width, n, n)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, svgColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, svgColor(opts.Foreground))
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			// Соседние темные модули строки объединяются в один прямоугольник
+			run := 1
+			for x+run < c.Size && c.modules[y][x+run] {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d,%dh%dv1h-%dz", x+opts.QuietZone, y+opts.QuietZone, run, run)
+			x += run - 1
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo != nil {
+		logo, plate := c.logoRect(opts, opts.Logo.Bounds())
+		if !logo.Empty() {
+			scale := float64(opts.ModuleSize)
+			fmt.Fprintf(&buf, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`,
+				float64(plate.Min.X)/scale, float64(plate.Min.Y)/scale, float64(plate.Dx())/scale, float64(plate.Dy())/scale,
+				svgColor(opts.Background))
+
+			var logoPNG bytes.Buffer
+			if png.Encode(&logoPNG, opts.Logo) == nil {
+				fmt.Fprintf(&buf, `<image x="%g" y="%g" width="%g" height="%g" href="data:image/png;base64,%s"/>`,
+					float64(logo.Min.X)/scale, float64(logo.Min.Y)/scale, float64(logo.Dx())/scale, float64(logo.Dy())/scale,
+					base64.StdEncoding.EncodeToString(logoPNG.Bytes()))
+			}
+		}
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+func svgColor(c color.Color) string {
+	r, g, b, a := c.RGBA()
+	if a == 0xffff {
+		return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3f)", r>>8, g>>8, b>>8, float64(a)/0xffff)
+}
+
+// Terminal возвращает QR код для вывода в терминал: каждый символ содержит два модуля по вертикали (символы
+// полублоков Unicode). Рассчитан на терминал с темным фоном: светлые модули выводятся закрашенными. Включает отступ
+// в 2 модуля.
+func (c *Code) Terminal() string {
+	const quiet = 2
+
+	var sb strings.Builder
+	for y := -quiet; y < c.Size+quiet; y += 2 {
+		for x := -quiet; x < c.Size+quiet; x++ {
+			top, bottom := !c.Dark(x, y), !c.Dark(x, y+1)
+			if y+1 >= c.Size+quiet {
+				bottom = false
+			}
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ASCII возвращает QR код из символов ASCII: темный модуль - "##", светлый - два пробела. Включает отступ в 2 модуля.
+// Подходит для логов и терминалов без поддержки Unicode.
+func (c *Code) ASCII() string {
+	const quiet = 2
+
+	var sb strings.Builder
+	for y := -quiet; y < c.Size+quiet; y++ {
+		for x := -quiet; x < c.Size+quiet; x++ {
+			if c.Dark(x, y) {
+				sb.WriteString("##")
+			} else {
+				sb.WriteString("  ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,192 @@
+package oacquiring
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRetryAfter - пауза после ответа 429 Too Many Requests без заголовка Retry-After
+	defaultRetryAfter = time.Second
+	// minRateFactor - минимальная доля исходной частоты запросов, до которой снижается частота после ответов 429
+	minRateFactor = 0.1
+	// rateRecoveryFactor - доля исходной частоты запросов, на которую частота увеличивается после успешного ответа
+	rateRecoveryFactor = 0.05
+)
+
+type (
+	// RateLimit - ограничения частоты и количества одновременных запросов к серверу Оплати. Для подключения
+	// используйте WithRateLimit.
+	//
+	// Если сервер ответил 429 Too Many Requests, запросы приостанавливаются на время из заголовка Retry-After (или на
+	// 1 секунду, если заголовок отсутствует), а частота запросов уменьшается вдвое (но не ниже 10% от Rate) и затем
+	// постепенно восстанавливается после успешных ответов. Пауза соблюдается и при Rate == 0.
+	RateLimit struct {
+		Rate        float64 // Максимальное среднее количество запросов в секунду. 0 - без ограничения
+		Burst       int     // Максимальное количество запросов, которые можно выполнить сразу. Если < 1, используется 1
+		MaxInFlight int     // Максимальное количество одновременных запросов. 0 - без ограничения
+	}
+
+	// rateLimiters - ограничители запросов Client по операциям
+	rateLimiters struct {
+		byOperation map[Operation]*limiter
+		fallback    *limiter
+	}
+
+	// limiter - token bucket с семафором одновременных запросов
+	limiter struct {
+		baseRate float64
+		burst    float64
+		inFlight chan struct{}
+
+		mu          sync.Mutex
+		rate        float64
+		tokens      float64
+		last        time.Time
+		pausedUntil time.Time
+	}
+)
+
+func newLimiter(limit RateLimit) *limiter {
+	l := &limiter{
+		baseRate: limit.Rate,
+		rate:     limit.Rate,
+		burst:    math.Max(float64(limit.Burst), 1),
+	}
+	l.tokens = l.burst
+
+	if limit.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+
+	return l
+}
+
+// get возвращает ограничитель для операции op либо nil, если ограничений нет
+func (rl *rateLimiters) get(op Operation) *limiter {
+	if rl == nil {
+		return nil
+	}
+	if l, ok := rl.byOperation[op]; ok {
+		return l
+	}
+	return rl.fallback
+}
+
+// wait ожидает возможности выполнить запрос. Если ожидание прервано отменой ctx, возвращается ошибка ctx. После
+// выполнения запроса необходимо вызвать release.
+func (l *limiter) wait(ctx context.Context) error {
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	delay := l.reserve()
+	if !sleep(ctx, delay) {
+		l.cancelReservation()
+		l.release()
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// release освобождает место для следующего одновременного запроса
+func (l *limiter) release() {
+	if l.inFlight != nil {
+		<-l.inFlight
+	}
+}
+
+// reserve резервирует токен и возвращает время, через которое его можно использовать
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	var delay time.Duration
+	if now.Before(l.pausedUntil) {
+		delay = l.pausedUntil.Sub(now)
+	}
+
+	if l.rate <= 0 {
+		return delay
+	}
+
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens < 0 {
+		delay = max(delay, time.Duration(-l.tokens/l.rate*float64(time.Second)))
+	}
+
+	return delay
+}
+
+func (l *limiter) cancelReservation() {
+	if l.rate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// observe адаптирует частоту запросов по ответу сервера
+func (l *limiter) observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		if l.rate < l.baseRate {
+			l.rate = math.Min(l.baseRate, l.rate+l.baseRate*rateRecoveryFactor)
+		}
+		return
+	}
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+	if until := time.Now().Add(retryAfter); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+
+	if l.baseRate > 0 {
+		l.rate = math.Max(l.baseRate*minRateFactor, l.rate/2)
+		l.tokens = math.Min(l.tokens, 0)
+	}
+}
+
+// parseRetryAfter разбирает значение заголовка Retry-After: количество секунд или HTTP дату. Возвращает 0, если
+// значение отсутствует или некорректно.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,13 @@
+module github.com/oplati-by/go-acquiring/receipt
+
+go 1.23.8
+
+require (
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/oplati-by/go-acquiring v0.0.0-20261019104813-efa79e84e7a2
+)
+
+require (
+	github.com/kr/text v0.2.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,171 @@
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfFontFamily = "receipt"
+	pdfPageWidth  = 80.0 // Ширина страницы в мм, как у чековой ленты 80 мм
+	pdfMargin     = 4.0
+	pdfFontSize   = 9.0
+	pdfLineHeight = 4.2
+	pdfGap        = 2.0 // Минимальный отступ между текстом слева и справа в строке
+)
+
+// pdfRowKind - вид строки PDF чека
+type pdfRowKind int
+
+const (
+	pdfCenter pdfRowKind = iota
+	pdfPair
+	pdfText
+	pdfRule
+)
+
+type pdfRow struct {
+	kind        pdfRowKind
+	left, right string
+}
+
+// WritePDF записывает чек в виде PDF документа шириной 80 мм. Высота страницы соответствует длине чека. Для PDF
+// необходим шрифт, переданный в WithPDFFont.
+func (r *Renderer) WritePDF(w io.Writer, receipt Receipt) error {
+	if len(r.pdfFont) == 0 {
+		return errors.New("pdf font is not configured, use WithPDFFont")
+	}
+
+	rows := pdfRows(r.page(receipt))
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: pdfPageWidth, Ht: pdfPageWidth},
+	})
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetCreator("go-acquiring", true)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", r.pdfFont)
+	pdf.SetFont(pdfFontFamily, "", pdfFontSize)
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("pdf font loading failed: %w", err)
+	}
+
+	height := layoutPDF(pdf, rows, false)
+	pdf.AddPageFormat("P", fpdf.SizeType{Wd: pdfPageWidth, Ht: height + 2*pdfMargin})
+	layoutPDF(pdf, rows, true)
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("pdf receipt rendering failed: %w", err)
+	}
+	return nil
+}
+
+// layoutPDF размещает строки чека и возвращает их общую высоту. Если draw == false, строки только измеряются.
+func layoutPDF(pdf *fpdf.Fpdf, rows []pdfRow, draw bool) float64 {
+	width := pdfPageWidth - 2*pdfMargin
+	y := pdfMargin
+
+	line := func(text, align string) {
+		if draw {
+			pdf.SetXY(pdfMargin, y)
+			pdf.CellFormat(width, pdfLineHeight, text, "", 0, align, false, 0, "")
+		}
+		y += pdfLineHeight
+	}
+
+	for _, row := range rows {
+		switch row.kind {
+		case pdfCenter:
+			for _, text := range pdf.SplitText(row.left, width) {
+				line(text, "C")
+			}
+		case pdfText:
+			for _, text := range pdf.SplitText(row.left, width) {
+				line(text, "L")
+			}
+		case pdfRule:
+			if draw {
+				pdf.SetDashPattern([]float64{1, 1}, 0)
+				pdf.Line(pdfMargin, y+pdfLineHeight/2, pdfMargin+width, y+pdfLineHeight/2)
+				pdf.SetDashPattern(nil, 0)
+			}
+			y += pdfLineHeight
+		case pdfPair:
+			lines := pdf.SplitText(row.left, width)
+			if len(lines) == 0 {
+				lines = []string{""}
+			}
+			for _, text := range lines[:len(lines)-1] {
+				line(text, "L")
+			}
+
+			last := lines[len(lines)-1]
+			if pdf.GetStringWidth(last)+pdfGap+pdf.GetStringWidth(row.right) > width {
+				line(last, "L")
+				line(row.right, "R")
+				continue
+			}
+			if draw {
+				pdf.SetXY(pdfMargin, y)
+				pdf.CellFormat(width, pdfLineHeight, last, "", 0, "L", false, 0, "")
+				pdf.SetXY(pdfMargin, y)
+				pdf.CellFormat(width, pdfLineHeight, row.right, "", 0, "R", false, 0, "")
+			}
+			y += pdfLineHeight
+		}
+	}
+
+	return y - pdfMargin
+}
+
+// pdfRows возвращает строки PDF чека. Состав строк совпадает с текстовым шаблоном по умолчанию.
+func pdfRows(p Page) []pdfRow {
+	var rows []pdfRow
+	add := func(kind pdfRowKind, left, right string) {
+		rows = append(rows, pdfRow{kind: kind, left: left, right: right})
+	}
+	field := func(label, value string) {
+		if value != "" {
+			add(pdfPair, label, value)
+		}
+	}
+
+	if p.Brand.Name != "" {
+		add(pdfCenter, p.Brand.Name, "")
+	}
+	for _, brandLine := range p.Brand.Lines {
+		add(pdfCenter, brandLine, "")
+	}
+
+	add(pdfCenter, "КАССОВЫЙ ЧЕК", "")
+	add(pdfCenter, p.Operation(), "")
+	add(pdfRule, "", "")
+	add(pdfPair, "Касса", p.RegNum)
+	field("Смена", p.Shift)
+	field("Заказ", p.OrderNumber)
+	if p.PaymentId != 0 {
+		field("Платеж Оплати", fmt.Sprint(p.PaymentId))
+	}
+	field("Дата", p.DateText())
+	add(pdfRule, "", "")
+
+	for _, item := range p.Items {
+		add(pdfPair, item.Name, FormatSum(item.Cost))
+	}
+	add(pdfRule, "", "")
+
+	add(pdfPair, "ИТОГО", FormatSum(p.Total)+" BYN")
+	add(pdfPair, "Оплата", "Оплати")
+	add(pdfPair, "Статус", p.StatusText())
+
+	if p.FooterText != "" {
+		add(pdfRule, "", "")
+		add(pdfText, p.FooterText, "")
+	}
+
+	return rows
+}
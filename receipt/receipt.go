@@ -0,0 +1,169 @@
+// Package receipt формирует чек по платежу Оплати в виде текста фиксированной ширины (для чековых принтеров), HTML и
+// PDF. Чек содержит регистрационный номер кассы, смену, позиции, итог и ReceiptFooterText.
+//
+//	r := receipt.FromPayment(regNum, payment, paymentInfo)
+//	renderer, err := receipt.New(receipt.WithBrand(receipt.Brand{Name: "ООО Магазин", Lines: []string{"УНП 123456789"}}))
+//	// ...
+//	err = renderer.WriteText(os.Stdout, r)
+//
+// Для PDF необходимо передать TrueType шрифт с кириллицей (WithPDFFont), т.к. стандартные шрифты PDF ее не содержат.
+// Внешний вид текстового и HTML чека можно изменить, переопределив блоки шаблонов (WithTextTemplate,
+// WithHTMLTemplate).
+package receipt
+
+import (
+	"strconv"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+type (
+	// Receipt - данные чека. Для создания используйте FromPayment, FromPOSPayment или FromReversal.
+	Receipt struct {
+		RegNum        string                   // Регистрационный номер кассы
+		Shift         string                   // Смена
+		OrderNumber   string                   // Номер заказа
+		PaymentId     int64                    // Идентификатор платежа в Оплати
+		Type          oacquiring.PaymentType   // Тип кассовой операции
+		Status        oacquiring.PaymentStatus // Статус платежа
+		Date          time.Time                // Дата оплаты (или создания, если оплата не выполнена) в MinskLocation
+		Items         []oacquiring.PaymentItem // Позиции чека
+		Total         int64                    // Итог в копейках
+		PursePublicId string                   // Публичный идентификатор кошелька покупателя
+		FooterText    string                   // Дополнительная информация в конце чека (ReceiptFooterText)
+	}
+
+	// Brand - данные продавца, выводимые в начале чека
+	Brand struct {
+		Name  string   // Название продавца, например ООО "Магазин"
+		Lines []string // Дополнительные строки: УНП, адрес, телефон и т.п.
+	}
+
+	// Page - данные, передаваемые в шаблоны чека
+	Page struct {
+		Receipt
+		Brand Brand
+		Width int // Ширина текстового чека в символах
+	}
+)
+
+// FromPayment возвращает чек по платежу payment, созданному кассой regNum, и его статусу info
+func FromPayment(regNum string, payment oacquiring.Payment, info oacquiring.PaymentInfo) Receipt {
+	return newReceipt(regNum, payment.Shift, payment.OrderNumber, payment.Items, payment.ReceiptFooterText, info)
+}
+
+// FromPOSPayment возвращает чек по платежу payment на кассе магазина regNum и его статусу info
+func FromPOSPayment(regNum string, payment oacquiring.POSPayment, info oacquiring.PaymentInfo) Receipt {
+	return newReceipt(regNum, payment.Shift, payment.OrderNumber, payment.Items, payment.ReceiptFooterText, info)
+}
+
+// FromReversal возвращает чек по возврату reversal, выполненному кассой regNum, и результату info, полученному от
+// oacquiring.Client.ReversePayment
+func FromReversal(regNum string, reversal oacquiring.PaymentReversal, info oacquiring.PaymentInfo) Receipt {
+	return newReceipt(regNum, reversal.Shift, reversal.OrderNumber, reversal.Items, reversal.ReceiptFooterText, info)
+}
+
+func newReceipt(regNum, shift, orderNumber string, items []oacquiring.PaymentItem, footer string, info oacquiring.PaymentInfo) Receipt {
+	r := Receipt{
+		RegNum:        regNum,
+		Shift:         shift,
+		OrderNumber:   orderNumber,
+		PaymentId:     info.Id,
+		Type:          info.Type,
+		Status:        info.Status,
+		Items:         items,
+		PursePublicId: info.PursePublicId,
+		FooterText:    footer,
+	}
+
+	if r.OrderNumber == "" {
+		r.OrderNumber = info.OrderNumber
+	}
+
+	r.Date = info.PaidDate
+	if r.Date.IsZero() {
+		r.Date = info.CreatedDate
+	}
+	if !r.Date.IsZero() {
+		r.Date = r.Date.In(oacquiring.MinskLocation)
+	}
+
+	for _, item := range items {
+		r.Total += item.Cost
+	}
+	if len(items) == 0 {
+		r.Total = info.Sum
+	}
+
+	return r
+}
+
+// Operation возвращает название кассовой операции, например "ПРОДАЖА"
+func (r Receipt) Operation() string {
+	switch r.Type {
+	case oacquiring.PaymentTypeSell:
+		return "ПРОДАЖА"
+	case oacquiring.PaymentTypeBuy:
+		return "ПОКУПКА"
+	case oacquiring.PaymentItemTypeSellReverse:
+		return "ВОЗВРАТ ПРОДАЖИ"
+	case oacquiring.PaymentItemTypeBuyReverse:
+		return "ВОЗВРАТ ПОКУПКИ"
+	default:
+		return "ОПЕРАЦИЯ " + strconv.Itoa(int(r.Type))
+	}
+}
+
+// StatusText возвращает описание статуса платежа, например "Оплачено"
+func (r Receipt) StatusText() string {
+	switch r.Status {
+	case oacquiring.PaymentStatusInProgress:
+		return "Ожидает подтверждения"
+	case oacquiring.PaymentStatusDone:
+		return "Оплачено"
+	case oacquiring.PaymentStatusDeclined:
+		return "Отказ от платежа"
+	case oacquiring.PaymentStatusNotEnoughMoney:
+		return "Недостаточно средств"
+	case oacquiring.PaymentStatusTimeout:
+		return "Платеж не подтвержден вовремя"
+	case oacquiring.PaymentStatusTechCancel:
+		return "Платеж отменен"
+	default:
+		return r.Status.String()
+	}
+}
+
+// DateText возвращает дату чека в формате ДД.ММ.ГГГГ ЧЧ:ММ:СС либо пустую строку, если дата неизвестна
+func (r Receipt) DateText() string {
+	if r.Date.IsZero() {
+		return ""
+	}
+	return r.Date.Format("02.01.2006 15:04:05")
+}
+
+// ItemType возвращает название типа позиции: "Товар" или "Услуга"
+func ItemType(t oacquiring.PaymentItemType) string {
+	switch t {
+	case oacquiring.PaymentItemTypeProduct:
+		return "Товар"
+	case oacquiring.PaymentItemTypeService:
+		return "Услуга"
+	default:
+		return ""
+	}
+}
+
+// FormatSum форматирует сумму в копейках как рубли, например 545 -> "5.45"
+func FormatSum(sum int64) string {
+	sign := ""
+	if sum < 0 {
+		sign, sum = "-", -sum
+	}
+	cents := strconv.FormatInt(sum%100, 10)
+	if len(cents) == 1 {
+		cents = "0" + cents
+	}
+	return sign + strconv.FormatInt(sum/100, 10) + "." + cents
+}
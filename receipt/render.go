@@ -0,0 +1,269 @@
+package receipt
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultWidth - ширина текстового чека по умолчанию (чековая лента 58 мм)
+	DefaultWidth = 32
+	// minWidth - минимальная ширина текстового чека
+	minWidth = 16
+)
+
+type (
+	// Renderer формирует чеки в виде текста, HTML и PDF. Безопасен для конкурентного использования. Для
+	// инициализации используйте New.
+	Renderer struct {
+		width   int
+		brand   Brand
+		pdfFont []byte
+
+		textOverrides []string
+		htmlOverrides []string
+
+		text *texttemplate.Template
+		html *htmltemplate.Template
+	}
+
+	// Opt - дополнительные параметры Renderer
+	Opt func(*Renderer)
+)
+
+// WithWidth - ширина текстового чека в символах. По умолчанию DefaultWidth (32, чековая лента 58 мм), для ленты 80 мм
+// обычно используется 48
+func WithWidth(width int) Opt {
+	return func(r *Renderer) {
+		r.width = width
+	}
+}
+
+// WithBrand - данные продавца, выводимые в начале чека
+func WithBrand(brand Brand) Opt {
+	return func(r *Renderer) {
+		r.brand = brand
+	}
+}
+
+// WithPDFFont - TrueType шрифт для PDF (например DejaVuSans.ttf). Шрифт должен содержать кириллицу. Без шрифта
+// WritePDF возвращает ошибку
+func WithPDFFont(ttf []byte) Opt {
+	return func(r *Renderer) {
+		r.pdfFont = ttf
+	}
+}
+
+// WithTextTemplate - переопределяет блоки текстового шаблона чека. tmpl содержит определения
+// {{define "имя"}}...{{end}} для блоков:
+//   - header - начало чека, по умолчанию название и строки Brand
+//   - footer - конец чека после ReceiptFooterText, по умолчанию пустой
+//   - receipt - чек целиком
+//
+// Шаблон получает Page. Доступны функции center, pair, wrap и rule (первый аргумент - ширина .Width), sum
+// (FormatSum) и itemType (ItemType). Может быть указан несколько раз.
+func WithTextTemplate(tmpl string) Opt {
+	return func(r *Renderer) {
+		r.textOverrides = append(r.textOverrides, tmpl)
+	}
+}
+
+// WithHTMLTemplate - переопределяет блоки HTML шаблона чека: style (CSS), header, footer и receipt (см.
+// WithTextTemplate). Доступны функции sum и itemType. Может быть указан несколько раз.
+func WithHTMLTemplate(tmpl string) Opt {
+	return func(r *Renderer) {
+		r.htmlOverrides = append(r.htmlOverrides, tmpl)
+	}
+}
+
+// New возвращает новый Renderer. Ошибка возвращается, если переданные шаблоны некорректны.
+//   - opts - Дополнительные настройки: WithWidth, WithBrand, WithPDFFont, WithTextTemplate, WithHTMLTemplate
+func New(opts ...Opt) (*Renderer, error) {
+	r := &Renderer{width: DefaultWidth}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.width < minWidth {
+		r.width = minWidth
+	}
+
+	var err error
+	r.text = texttemplate.New("page").Funcs(textFuncs)
+	for _, tmpl := range append([]string{defaultTextTemplate}, r.textOverrides...) {
+		if r.text, err = r.text.Parse(tmpl); err != nil {
+			return nil, fmt.Errorf("text template parsing failed: %w", err)
+		}
+	}
+
+	r.html = htmltemplate.New("page").Funcs(htmlFuncs)
+	for _, tmpl := range append([]string{defaultHTMLTemplate}, r.htmlOverrides...) {
+		if r.html, err = r.html.Parse(tmpl); err != nil {
+			return nil, fmt.Errorf("html template parsing failed: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// WriteText записывает чек в виде текста фиксированной ширины
+func (r *Renderer) WriteText(w io.Writer, receipt Receipt) error {
+	if err := r.text.Execute(w, r.page(receipt)); err != nil {
+		return fmt.Errorf("text receipt rendering failed: %w", err)
+	}
+	return nil
+}
+
+// WriteHTML записывает чек в виде HTML страницы
+func (r *Renderer) WriteHTML(w io.Writer, receipt Receipt) error {
+	if err := r.html.Execute(w, r.page(receipt)); err != nil {
+		return fmt.Errorf("html receipt rendering failed: %w", err)
+	}
+	return nil
+}
+
+func (r *Renderer) page(receipt Receipt) Page {
+	return Page{Receipt: receipt, Brand: r.brand, Width: r.width}
+}
+
+var (
+	textFuncs = texttemplate.FuncMap{
+		"center":   center,
+		"pair":     pair,
+		"wrap":     func(width int, s string) string { return strings.Join(wrap(s, width), "\n") },
+		"rule":     func(width int) string { return strings.Repeat("-", width) },
+		"sum":      FormatSum,
+		"itemType": ItemType,
+	}
+
+	htmlFuncs = htmltemplate.FuncMap{
+		"sum":      FormatSum,
+		"itemType": ItemType,
+	}
+)
+
+// center выравнивает s по центру строки шириной width. Длинный текст переносится.
+func center(width int, s string) string {
+	lines := wrap(s, width)
+	for i, line := range lines {
+		lines[i] = strings.Repeat(" ", (width-utf8.RuneCountInString(line))/2) + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pair возвращает строку шириной width с left слева и right справа. Если текст не помещается, left переносится, а
+// right выводится справа на последней строке или на отдельной строке.
+func pair(width int, left, right string) string {
+	lines := wrap(left, width)
+	last := lines[len(lines)-1]
+
+	gap := width - utf8.RuneCountInString(last) - utf8.RuneCountInString(right)
+	if gap >= 1 {
+		lines[len(lines)-1] = last + strings.Repeat(" ", gap) + right
+	} else {
+		lines = append(lines, strings.Repeat(" ", max(width-utf8.RuneCountInString(right), 0))+right)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// wrap разбивает s на строки не длиннее width символов по пробелам. Слова длиннее width разбиваются.
+func wrap(s string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+const defaultTextTemplate = `{{define "header"}}{{with .Brand.Name}}{{center $.Width .}}
+{{end}}{{range .Brand.Lines}}{{center $.Width .}}
+{{end}}{{end}}{{define "footer"}}{{end}}{{define "receipt"}}{{template "header" .}}{{center .Width "КАССОВЫЙ ЧЕК"}}
+{{center .Width .Operation}}
+{{rule .Width}}
+{{pair .Width "Касса" .RegNum}}
+{{with .Shift}}{{pair $.Width "Смена" .}}
+{{end}}{{with .OrderNumber}}{{pair $.Width "Заказ" .}}
+{{end}}{{with .PaymentId}}{{pair $.Width "Платеж Оплати" (print .)}}
+{{end}}{{with .DateText}}{{pair $.Width "Дата" .}}
+{{end}}{{rule .Width}}
+{{range .Items}}{{pair $.Width .Name (sum .Cost)}}
+{{end}}{{rule .Width}}
+{{pair .Width "ИТОГО" (print (sum .Total) " BYN")}}
+{{pair .Width "Оплата" "Оплати"}}
+{{pair .Width "Статус" .StatusText}}
+{{with .FooterText}}{{rule $.Width}}
+{{wrap $.Width .}}
+{{end}}{{template "footer" .}}{{end}}{{template "receipt" .}}`
+
+const defaultHTMLTemplate = `{{define "style"}}
+body { font-family: monospace; background: #f4f4f4; }
+.receipt { max-width: 360px; margin: 16px auto; padding: 16px; background: #fff; border: 1px solid #ddd; }
+.center { text-align: center; }
+.brand { font-weight: bold; }
+table { width: 100%; border-collapse: collapse; }
+td { padding: 2px 0; vertical-align: top; }
+td.sum { text-align: right; white-space: nowrap; padding-left: 8px; }
+tr.total td { font-weight: bold; border-top: 1px dashed #999; padding-top: 6px; }
+.items { border-top: 1px dashed #999; border-bottom: 1px dashed #999; margin: 8px 0; }
+.type { color: #777; font-size: 85%; }
+.footer { border-top: 1px dashed #999; margin-top: 8px; padding-top: 8px; white-space: pre-wrap; }
+{{end}}{{define "header"}}{{with .Brand.Name}}<div class="center brand">{{.}}</div>
+{{end}}{{range .Brand.Lines}}<div class="center">{{.}}</div>
+{{end}}{{end}}{{define "footer"}}{{end}}{{define "receipt"}}<div class="receipt">
+{{template "header" .}}<div class="center"><strong>КАССОВЫЙ ЧЕК</strong><br>{{.Operation}}</div>
+<table>
+<tr><td>Касса</td><td class="sum">{{.RegNum}}</td></tr>
+{{with .Shift}}<tr><td>Смена</td><td class="sum">{{.}}</td></tr>
+{{end}}{{with .OrderNumber}}<tr><td>Заказ</td><td class="sum">{{.}}</td></tr>
+{{end}}{{with .PaymentId}}<tr><td>Платеж Оплати</td><td class="sum">{{.}}</td></tr>
+{{end}}{{with .DateText}}<tr><td>Дата</td><td class="sum">{{.}}</td></tr>
+{{end}}</table>
+<table class="items">
+{{range .Items}}<tr><td>{{.Name}}{{with itemType .Type}} <span class="type">{{.}}</span>{{end}}</td><td class="sum">{{sum .Cost}}</td></tr>
+{{end}}</table>
+<table>
+<tr class="total"><td>ИТОГО</td><td class="sum">{{sum .Total}} BYN</td></tr>
+<tr><td>Оплата</td><td class="sum">Оплати</td></tr>
+<tr><td>Статус</td><td class="sum">{{.StatusText}}</td></tr>
+</table>
+{{with .FooterText}}<div class="footer">{{.}}</div>
+{{end}}{{template "footer" .}}</div>
+{{end}}<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Чек{{with .OrderNumber}} {{.}}{{end}}</title>
+<style>{{template "style" .}}</style>
+</head>
+<body>
+{{template "receipt" .}}</body>
+</html>
+`
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+type (
+	// Memory - PaymentRepository в памяти. Подходит для тестов и приложений без БД. Для инициализации используйте
+	// NewMemory.
+	Memory struct {
+		mu            sync.RWMutex
+		payments      map[int64]Payment
+		byOrderNumber map[string]int64
+	}
+)
+
+// NewMemory возвращает новый пустой Memory
+func NewMemory() *Memory {
+	return &Memory{
+		payments:      make(map[int64]Payment),
+		byOrderNumber: make(map[string]int64),
+	}
+}
+
+// Create реализует PaymentRepository
+func (m *Memory) Create(_ context.Context, payment Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.payments[payment.Id]; ok {
+		return nil
+	}
+
+	now := time.Now()
+	payment.CreatedAt, payment.UpdatedAt = now, now
+	m.store(payment)
+
+	return nil
+}
+
+// ApplyInfo реализует PaymentRepository
+func (m *Memory) ApplyInfo(_ context.Context, info oacquiring.PaymentInfo) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	current, ok := m.payments[info.Id]
+	if !ok {
+		payment := paymentFromInfo(info)
+		payment.CreatedAt, payment.UpdatedAt = now, now
+		m.store(payment)
+		return true, nil
+	}
+
+	if !oacquiring.CanTransition(current.Status, info.Status) {
+		return false, nil
+	}
+
+	updated := paymentFromInfo(info)
+	updated.Shift = current.Shift
+	updated.ReversedSum = current.ReversedSum
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = now
+	if updated.OrderNumber == "" {
+		updated.OrderNumber = current.OrderNumber
+	}
+	if updated.Sum == 0 {
+		updated.Sum = current.Sum
+	}
+
+	if current.OrderNumber != updated.OrderNumber && m.byOrderNumber[current.OrderNumber] == current.Id {
+		delete(m.byOrderNumber, current.OrderNumber)
+	}
+	m.store(updated)
+
+	return current.Status != updated.Status, nil
+}
+
+// AddReversal реализует PaymentRepository
+func (m *Memory) AddReversal(_ context.Context, paymentId int64, sum int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	payment, ok := m.payments[paymentId]
+	if !ok {
+		return ErrNotFound
+	}
+
+	payment.ReversedSum += sum
+	payment.UpdatedAt = time.Now()
+	m.payments[paymentId] = payment
+
+	return nil
+}
+
+// Touch реализует PaymentRepository
+func (m *Memory) Touch(_ context.Context, paymentId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	payment, ok := m.payments[paymentId]
+	if !ok {
+		return ErrNotFound
+	}
+
+	payment.UpdatedAt = time.Now()
+	m.payments[paymentId] = payment
+
+	return nil
+}
+
+// Get реализует PaymentRepository
+func (m *Memory) Get(_ context.Context, paymentId int64) (Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	payment, ok := m.payments[paymentId]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+	return payment, nil
+}
+
+// GetByOrderNumber реализует PaymentRepository
+func (m *Memory) GetByOrderNumber(_ context.Context, orderNumber string) (Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.byOrderNumber[orderNumber]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+	return m.payments[id], nil
+}
+
+// ListByStatus реализует PaymentRepository
+func (m *Memory) ListByStatus(_ context.Context, status oacquiring.PaymentStatus, limit int) ([]Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var payments []Payment
+	for _, payment := range m.payments {
+		if payment.Status == status {
+			payments = append(payments, payment)
+		}
+	}
+
+	slices.SortFunc(payments, func(a, b Payment) int {
+		return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), cmp.Compare(a.Id, b.Id))
+	})
+	if limit > 0 && len(payments) > limit {
+		payments = payments[:limit]
+	}
+
+	return payments, nil
+}
+
+// store сохраняет payment. Возвраты не попадают в индекс по номеру заказа, т.к. их номер совпадает с номером исходного
+// платежа.
+func (m *Memory) store(payment Payment) {
+	m.payments[payment.Id] = payment
+	if payment.OrderNumber != "" && !isReversal(payment.Type) {
+		m.byOrderNumber[payment.OrderNumber] = payment.Id
+	}
+}
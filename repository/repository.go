@@ -0,0 +1,90 @@
+// Package repository содержит хранилище платежей Оплати (PaymentRepository) с реализациями в памяти (NewMemory) и на
+// основе database/sql для PostgreSQL и SQLite (NewSQL), а также Syncer, автоматически сохраняющий в хранилище
+// результаты операций oacquiring.Client и уведомления.
+//
+//	db, err := sql.Open("postgres", dsn)
+//	// ...
+//	err = repository.Migrate(ctx, db, repository.Postgres)
+//	// ...
+//	syncer := repository.NewSyncer(repository.NewSQL(db, repository.Postgres), func(err error) { log.Print(err) })
+//
+//	oplatiClient := oacquiring.NewClient(baseUrl, regNum, password, oacquiring.WithClientHooks(syncer.ClientHooks()))
+//	handler, err := oacquiring.NewHTTPNotificationHandler(key, syncer.NotificationHandler(&Handler{}))
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+// ErrNotFound - платеж не найден в хранилище
+var ErrNotFound = errors.New("payment not found")
+
+type (
+	// Payment - сохраненный платеж
+	Payment struct {
+		Id            int64                    // Идентификатор платежа в Оплати
+		OrderNumber   string                   // Уникальный номер заказа
+		Shift         string                   // Смена, если платеж создан через oacquiring.Client
+		Type          oacquiring.PaymentType   // Тип платежа
+		Status        oacquiring.PaymentStatus // Статус платежа
+		Sum           int64                    // Сумма в копейках
+		ReversedSum   int64                    // Сумма выполненных возвратов в копейках
+		PursePublicId string                   // Публичный идентификатор кошелька
+		CreatedDate   time.Time                // Дата создания платежа в Оплати
+		PaidDate      time.Time                // Дата выполнения оплаты
+		CreatedAt     time.Time                // Время сохранения платежа в хранилище
+		UpdatedAt     time.Time                // Время последнего изменения платежа в хранилище
+	}
+
+	// PaymentRepository - хранилище платежей. Реализации должны быть безопасны для конкурентного использования.
+	PaymentRepository interface {
+		// Create сохраняет новый платеж. Если платеж с таким Id уже существует, он не изменяется.
+		Create(ctx context.Context, payment Payment) error
+
+		// ApplyInfo обновляет платеж по данным Оплати, если переход статуса допустим (oacquiring.CanTransition), и
+		// возвращает true, если платеж создан или изменился его статус. Повторные данные с тем же статусом обновляют
+		// остальные поля платежа, но возвращают false.
+		ApplyInfo(ctx context.Context, info oacquiring.PaymentInfo) (bool, error)
+
+		// AddReversal увеличивает сумму возвратов платежа на sum. Если платеж отсутствует, возвращается ErrNotFound.
+		AddReversal(ctx context.Context, paymentId int64, sum int64) error
+
+		// Get возвращает платеж по идентификатору. Если платеж отсутствует, возвращается ErrNotFound.
+		Get(ctx context.Context, paymentId int64) (Payment, error)
+
+		// GetByOrderNumber возвращает платеж по номеру заказа. Возвраты (например, из GetPaymentsOnShift) имеют тот же
+		// номер заказа, что и исходный платеж, и не возвращаются. Если платеж отсутствует, возвращается ErrNotFound.
+		GetByOrderNumber(ctx context.Context, orderNumber string) (Payment, error)
+
+		// Touch обновляет время изменения платежа (UpdatedAt), не изменяя его данных, например чтобы отметить
+		// неудачную проверку статуса. Если платеж отсутствует, возвращается ErrNotFound.
+		Touch(ctx context.Context, paymentId int64) error
+
+		// ListByStatus возвращает не более limit платежей со статусом status (все, если limit <= 0), начиная с
+		// наиболее давно измененных.
+		ListByStatus(ctx context.Context, status oacquiring.PaymentStatus, limit int) ([]Payment, error)
+	}
+)
+
+// isReversal возвращает true для операций возврата
+func isReversal(t oacquiring.PaymentType) bool {
+	return t == oacquiring.PaymentItemTypeSellReverse || t == oacquiring.PaymentItemTypeBuyReverse
+}
+
+// paymentFromInfo возвращает Payment с данными из PaymentInfo
+func paymentFromInfo(info oacquiring.PaymentInfo) Payment {
+	return Payment{
+		Id:            info.Id,
+		OrderNumber:   info.OrderNumber,
+		Type:          info.Type,
+		Status:        info.Status,
+		Sum:           info.Sum,
+		PursePublicId: info.PursePublicId,
+		CreatedDate:   info.CreatedDate,
+		PaidDate:      info.PaidDate,
+	}
+}
@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+const (
+	paymentsTable   = "oplati_payments"
+	migrationsTable = "oplati_schema_migrations"
+
+	paymentColumns = "id, order_number, shift, type, status, amount, reversed_amount, purse_public_id, created_date, paid_date, created_at, updated_at"
+
+	// applyInfoSet - обновление платежа данными Оплати. Пустые номер заказа и сумма не заменяют сохраненные
+	applyInfoSet = `order_number = COALESCE(NULLIF(CAST(? AS TEXT), ''), order_number),
+		type = ?,
+		status = ?,
+		amount = COALESCE(NULLIF(CAST(? AS BIGINT), 0), amount),
+		purse_public_id = ?,
+		created_date = ?,
+		paid_date = ?,
+		updated_at = ?`
+)
+
+var (
+	// Postgres - диалект PostgreSQL
+	Postgres = Dialect{
+		name:        "postgres",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		migrations: [][]string{
+			{
+				`CREATE TABLE IF NOT EXISTS oplati_payments (
+					id BIGINT PRIMARY KEY,
+					order_number TEXT NOT NULL DEFAULT '',
+					shift TEXT NOT NULL DEFAULT '',
+					type INTEGER NOT NULL DEFAULT 0,
+					status INTEGER NOT NULL DEFAULT 0,
+					amount BIGINT NOT NULL DEFAULT 0,
+					reversed_amount BIGINT NOT NULL DEFAULT 0,
+					purse_public_id TEXT NOT NULL DEFAULT '',
+					created_date TIMESTAMPTZ NULL,
+					paid_date TIMESTAMPTZ NULL,
+					created_at TIMESTAMPTZ NOT NULL,
+					updated_at TIMESTAMPTZ NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS oplati_payments_order_number_idx ON oplati_payments (order_number)`,
+				`CREATE INDEX IF NOT EXISTS oplati_payments_status_updated_at_idx ON oplati_payments (status, updated_at)`,
+			},
+		},
+	}
+
+	// SQLite - диалект SQLite (3.24 и новее)
+	SQLite = Dialect{
+		name:        "sqlite",
+		placeholder: func(int) string { return "?" },
+		migrations: [][]string{
+			{
+				`CREATE TABLE IF NOT EXISTS oplati_payments (
+					id INTEGER PRIMARY KEY,
+					order_number TEXT NOT NULL DEFAULT '',
+					shift TEXT NOT NULL DEFAULT '',
+					type INTEGER NOT NULL DEFAULT 0,
+					status INTEGER NOT NULL DEFAULT 0,
+					amount INTEGER NOT NULL DEFAULT 0,
+					reversed_amount INTEGER NOT NULL DEFAULT 0,
+					purse_public_id TEXT NOT NULL DEFAULT '',
+					created_date DATETIME NULL,
+					paid_date DATETIME NULL,
+					created_at DATETIME NOT NULL,
+					updated_at DATETIME NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS oplati_payments_order_number_idx ON oplati_payments (order_number)`,
+				`CREATE INDEX IF NOT EXISTS oplati_payments_status_updated_at_idx ON oplati_payments (status, updated_at)`,
+			},
+		},
+	}
+)
+
+type (
+	// Dialect - диалект SQL. Варианты: Postgres, SQLite
+	Dialect struct {
+		name        string
+		placeholder func(n int) string
+		migrations  [][]string
+	}
+
+	// SQL - PaymentRepository на основе database/sql. Перед использованием необходимо применить миграции с помощью
+	// Migrate. Для инициализации используйте NewSQL.
+	SQL struct {
+		db      *sql.DB
+		dialect Dialect
+	}
+
+	// rowScanner - *sql.Row или *sql.Rows
+	rowScanner interface {
+		Scan(dest ...any) error
+	}
+)
+
+func (d Dialect) String() string {
+	return d.name
+}
+
+// query заменяет плейсхолдеры "?" в query на плейсхолдеры диалекта
+func (d Dialect) query(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString(d.placeholder(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Migrate создает или обновляет таблицы хранилища платежей. Примененные миграции записываются в таблицу
+// oplati_schema_migrations, поэтому Migrate можно вызывать при каждом запуске приложения.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("migrations table creation failed: %w", err)
+	}
+
+	var version int
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM `+migrationsTable).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("schema version reading failed: %w", err)
+	}
+
+	for i := version; i < len(dialect.migrations); i++ {
+		err = applyMigration(ctx, db, dialect, i+1)
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, dialect Dialect, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, statement := range dialect.migrations[version-1] {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, dialect.query(`INSERT INTO `+migrationsTable+` (version) VALUES (?)`), version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NewSQL возвращает новый SQL
+func NewSQL(db *sql.DB, dialect Dialect) *SQL {
+	return &SQL{db: db, dialect: dialect}
+}
+
+// Create реализует PaymentRepository
+func (s *SQL) Create(ctx context.Context, payment Payment) error {
+	now := time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx, s.dialect.query(`INSERT INTO `+paymentsTable+` (`+paymentColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`),
+		payment.Id, payment.OrderNumber, payment.Shift, int(payment.Type), int(payment.Status), payment.Sum,
+		payment.ReversedSum, payment.PursePublicId, nullTime(payment.CreatedDate), nullTime(payment.PaidDate), now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("payment insertion failed: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyInfo реализует PaymentRepository. Проверка перехода статуса выполняется в том же запросе, что и обновление,
+// поэтому из параллельных вызовов с одним статусом true возвращает только один.
+func (s *SQL) ApplyInfo(ctx context.Context, info oacquiring.PaymentInfo) (bool, error) {
+	now := time.Now().UTC()
+	set := []any{info.OrderNumber, int(info.Type), int(info.Status), info.Sum, info.PursePublicId,
+		nullTime(info.CreatedDate), nullTime(info.PaidDate), now}
+
+	changed, err := s.exec(ctx, `UPDATE `+paymentsTable+` SET `+applyInfoSet+`
+		WHERE id = ? AND status = `+strconv.Itoa(oacquiring.PaymentStatusInProgress)+` AND status <> ?`,
+		append(set, info.Id, int(info.Status))...)
+	if err != nil || changed {
+		return changed, err
+	}
+
+	created, err := s.exec(ctx, `INSERT INTO `+paymentsTable+` (`+paymentColumns+`)
+		VALUES (?, ?, '', ?, ?, ?, 0, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		info.Id, info.OrderNumber, int(info.Type), int(info.Status), info.Sum, info.PursePublicId,
+		nullTime(info.CreatedDate), nullTime(info.PaidDate), now, now,
+	)
+	if err != nil || created {
+		return created, err
+	}
+
+	_, err = s.exec(ctx, `UPDATE `+paymentsTable+` SET `+applyInfoSet+` WHERE id = ? AND status = ?`,
+		append(set, info.Id, int(info.Status))...)
+	return false, err
+}
+
+// exec выполняет запрос, изменяющий платеж, и возвращает true, если была изменена хотя бы одна строка
+func (s *SQL) exec(ctx context.Context, query string, args ...any) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.dialect.query(query), args...)
+	if err != nil {
+		return false, fmt.Errorf("payment update failed: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("payment update failed: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// AddReversal реализует PaymentRepository
+func (s *SQL) AddReversal(ctx context.Context, paymentId int64, sum int64) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.query(`UPDATE `+paymentsTable+`
+		SET reversed_amount = reversed_amount + ?, updated_at = ?
+		WHERE id = ?`),
+		sum, time.Now().UTC(), paymentId,
+	)
+	if err != nil {
+		return fmt.Errorf("payment update failed: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("payment update failed: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Touch реализует PaymentRepository
+func (s *SQL) Touch(ctx context.Context, paymentId int64) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.query(`UPDATE `+paymentsTable+` SET updated_at = ? WHERE id = ?`),
+		time.Now().UTC(), paymentId,
+	)
+	if err != nil {
+		return fmt.Errorf("payment update failed: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("payment update failed: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Get реализует PaymentRepository
+func (s *SQL) Get(ctx context.Context, paymentId int64) (Payment, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.query(`SELECT `+paymentColumns+` FROM `+paymentsTable+` WHERE id = ?`), paymentId)
+	return scanPayment(row)
+}
+
+// GetByOrderNumber реализует PaymentRepository. Если платежей с таким номером заказа несколько, возвращается
+// последний измененный.
+func (s *SQL) GetByOrderNumber(ctx context.Context, orderNumber string) (Payment, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.query(`SELECT `+paymentColumns+` FROM `+paymentsTable+`
+		WHERE order_number = ? AND type NOT IN (`+strconv.Itoa(oacquiring.PaymentItemTypeSellReverse)+`, `+
+		strconv.Itoa(oacquiring.PaymentItemTypeBuyReverse)+`)
+		ORDER BY updated_at DESC
+		LIMIT 1`), orderNumber)
+	return scanPayment(row)
+}
+
+// ListByStatus реализует PaymentRepository
+func (s *SQL) ListByStatus(ctx context.Context, status oacquiring.PaymentStatus, limit int) ([]Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM ` + paymentsTable + ` WHERE status = ? ORDER BY updated_at, id`
+	args := []any{int(status)}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.query(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("payments query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var payments []Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("payments query failed: %w", err)
+	}
+
+	return payments, nil
+}
+
+func scanPayment(row rowScanner) (Payment, error) {
+	var (
+		payment               Payment
+		paymentType, status   int
+		createdDate, paidDate sql.NullTime
+		createdAt, updatedAt  time.Time
+	)
+
+	err := row.Scan(&payment.Id, &payment.OrderNumber, &payment.Shift, &paymentType, &status, &payment.Sum,
+		&payment.ReversedSum, &payment.PursePublicId, &createdDate, &paidDate, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Payment{}, ErrNotFound
+	}
+	if err != nil {
+		return Payment{}, fmt.Errorf("payment scanning failed: %w", err)
+	}
+
+	payment.Type = oacquiring.PaymentType(paymentType)
+	payment.Status = oacquiring.PaymentStatus(status)
+	payment.CreatedDate = createdDate.Time
+	payment.PaidDate = paidDate.Time
+	payment.CreatedAt = createdAt
+	payment.UpdatedAt = updatedAt
+
+	return payment, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
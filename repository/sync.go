@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+)
+
+type (
+	// Syncer сохраняет в PaymentRepository результаты операций oacquiring.Client (через ClientHooks) и уведомления
+	// (через NotificationHandler). Для инициализации используйте NewSyncer.
+	Syncer struct {
+		repo    PaymentRepository
+		onError func(error)
+	}
+
+	syncNotificationHandler struct {
+		syncer *Syncer
+		next   oacquiring.PaymentNotificationHandler
+	}
+)
+
+// NewSyncer возвращает новый Syncer. onError вызывается для ошибок сохранения результатов операций Client (хуки не
+// могут вернуть ошибку) и может быть nil.
+func NewSyncer(repo PaymentRepository, onError func(error)) *Syncer {
+	return &Syncer{repo: repo, onError: onError}
+}
+
+// ClientHooks возвращает oacquiring.ClientHooks, сохраняющие созданные платежи, полученные статусы и возвраты
+func (s *Syncer) ClientHooks() oacquiring.ClientHooks {
+	return oacquiring.ClientHooks{
+		PaymentCreated:      s.paymentCreated,
+		PaymentInfoReceived: s.paymentInfoReceived,
+		PaymentReversed:     s.paymentReversed,
+	}
+}
+
+// NotificationHandler возвращает oacquiring.PaymentNotificationHandler, который вызывает next (если next не nil) и
+// после успешной обработки сохраняет платеж из уведомления. Если next вернул ошибку или сохранить платеж не удалось,
+// возвращается ошибка, и сервер Оплати повторит уведомление; платеж при этом остается в прежнем статусе, поэтому next
+// получит его повторно (в т.ч. от sweeper.Sweeper). next видит в хранилище еще не обновленный платеж.
+func (s *Syncer) NotificationHandler(next oacquiring.PaymentNotificationHandler) oacquiring.PaymentNotificationHandler {
+	return &syncNotificationHandler{syncer: s, next: next}
+}
+
+func (h *syncNotificationHandler) HandlePayment(payment oacquiring.PaymentInfo) error {
+	return h.ReceiveNotification(context.Background(), oacquiring.Notification{Payment: payment})
+}
+
+// ReceiveNotification реализует oacquiring.NotificationReceiver, чтобы next получил полное уведомление
+func (h *syncNotificationHandler) ReceiveNotification(ctx context.Context, notification oacquiring.Notification) error {
+	if h.next != nil {
+		err := oacquiring.DeliverNotification(ctx, h.next, notification)
+		if err != nil {
+			return err
+		}
+	}
+
+	payment := notification.Payment
+	_, err := h.syncer.repo.ApplyInfo(ctx, payment)
+	if err != nil {
+		return fmt.Errorf("payment %d saving failed: %w", payment.Id, err)
+	}
+	return nil
+}
+
+func (s *Syncer) paymentCreated(ctx context.Context, payment oacquiring.Payment, result oacquiring.SuccessfulPayment) {
+	err := s.repo.Create(ctx, Payment{
+		Id:          result.PaymentId,
+		OrderNumber: payment.OrderNumber,
+		Shift:       payment.Shift,
+		Status:      oacquiring.PaymentStatusInProgress,
+		Sum:         itemsSum(payment.Items),
+	})
+	if err != nil {
+		s.error(fmt.Errorf("payment %d saving failed: %w", result.PaymentId, err))
+	}
+}
+
+func (s *Syncer) paymentInfoReceived(ctx context.Context, _ oacquiring.Operation, info oacquiring.PaymentInfo) {
+	_, err := s.repo.ApplyInfo(ctx, info)
+	if err != nil {
+		s.error(fmt.Errorf("payment %d saving failed: %w", info.Id, err))
+	}
+}
+
+func (s *Syncer) paymentReversed(ctx context.Context, paymentId int64, reversal oacquiring.PaymentReversal, _ oacquiring.PaymentInfo) {
+	err := s.repo.AddReversal(ctx, paymentId, itemsSum(reversal.Items))
+	if err != nil {
+		s.error(fmt.Errorf("payment %d reversal saving failed: %w", paymentId, err))
+	}
+}
+
+func (s *Syncer) error(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func itemsSum(items []oacquiring.PaymentItem) int64 {
+	var sum int64
+	for _, item := range items {
+		sum += item.Cost
+	}
+	return sum
+}
@@ -0,0 +1,132 @@
+package oacquiring
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type (
+	// RetryPolicy - настройки повтора запросов к серверу Оплати. Для подключения используйте WithRetry.
+	//
+	// Запросы, которые не изменяют данные (GetPaymentInfo, GetPaymentsOnShift), повторяются после ответа 429 Too Many
+	// Requests или 5xx (ServerError.Retryable) и после любой сетевой ошибки.
+	//
+	// Запросы, создающие или изменяющие платежи (CreatePayment, CreatePOSPayment, ReversePayment, CancelPayment),
+	// повторяются только после ответа 429 Too Many Requests и после сетевых ошибок, возникших до отправки запроса
+	// (ошибка соединения, DNS или TLS). Ответ 5xx или таймаут не означает, что сервер Оплати не выполнил операцию,
+	// поэтому повтор может создать дубликат платежа или повторный возврат. Чтобы все же повторять такие операции в
+	// этих случаях, укажите их в UnsafeOperations.
+	//
+	// Если ответ содержит заголовок Retry-After, пауза перед повтором не меньше указанной в нем.
+	RetryPolicy struct {
+		MaxAttempts      int           // Максимальное количество попыток, включая первую. 0 или 1 - без повторов
+		InitialBackoff   time.Duration // Пауза перед первым повтором. Каждая следующая пауза увеличивается вдвое
+		MaxBackoff       time.Duration // Максимальная пауза между попытками. 0 - без ограничения
+		UnsafeOperations []Operation   // Изменяющие операции, которые повторяются так же, как запросы без изменений
+	}
+
+	// executionError - ошибка выполнения HTTP запроса (сетевая ошибка, таймаут и т.п.)
+	executionError struct {
+		err error
+		// beforeSend - ошибка возникла до отправки запроса (соединение с сервером не было установлено)
+		beforeSend bool
+	}
+)
+
+func (e *executionError) Error() string {
+	return "request execution failed: " + e.err.Error()
+}
+
+func (e *executionError) Unwrap() error {
+	return e.err
+}
+
+// shouldRetry возвращает true, если после попытки attempt операции op, завершившейся ошибкой err, запрос нужно
+// повторить
+func (p RetryPolicy) shouldRetry(ctx context.Context, op Operation, method string, attempt int, err error) bool {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+
+	safe := method == http.MethodGet || slices.Contains(p.UnsafeOperations, op)
+
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		if safe {
+			return serverErr.Retryable()
+		}
+		return serverErr.httpStatus() == http.StatusTooManyRequests
+	}
+
+	var execErr *executionError
+	return errors.As(err, &execErr) && (safe || execErr.beforeSend)
+}
+
+// isConnectError возвращает true для ошибок установки соединения: DNS, соединения и TLS рукопожатия
+func isConnectError(err error) bool {
+	var (
+		opErr     *net.OpError
+		dnsErr    *net.DNSError
+		alertErr  tls.AlertError
+		recordErr tls.RecordHeaderError
+		certErr   *tls.CertificateVerificationError
+	)
+	switch {
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return true
+	case errors.As(err, &dnsErr), errors.As(err, &alertErr), errors.As(err, &recordErr), errors.As(err, &certErr):
+		return true
+	default:
+		return false
+	}
+}
+
+// isTransientError возвращает true для ошибок, свидетельствующих о временной недоступности сервера Оплати: сетевых
+// ошибок (кроме отмены контекста) и *ServerError с Retryable() == true
+func isTransientError(err error) bool {
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Retryable()
+	}
+
+	var execErr *executionError
+	return errors.As(err, &execErr) && !errors.Is(err, context.Canceled)
+}
+
+// backoff возвращает паузу перед попыткой attempt+1 со случайным отклонением до 50%
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleep ожидает d или отмены ctx. Возвращает false, если ctx был отменен.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -1,9 +1,7 @@
 package oacquiring
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -30,52 +28,24 @@ func (a *Client) ReversePayment(ctx context.Context, paymentId int64, payment Pa
 		return PaymentInfo{}, errors.New("at least one item should be specified in Items")
 	}
 
-	request := a.makeReversePaymentRequest(payment)
-
-	body, err := json.Marshal(&request)
+	creds, err := a.getCredentials(ctx)
 	if err != nil {
-		return PaymentInfo{}, fmt.Errorf("request encoding failed: %w", err)
+		return PaymentInfo{}, err
 	}
 
-	r, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseUrl+"/pos/payments/"+strconv.FormatInt(paymentId, 10)+"/reversals", bytes.NewReader(body))
-	if err != nil {
-		return PaymentInfo{}, fmt.Errorf("request initialization failed: %w", err)
-	}
-
-	r.Header.Set("RegNum", a.cashboxRegNumber)
-	r.Header.Set("Password", a.cashboxPassword)
-	r.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.httpClient.Do(r)
-	if err != nil {
-		return PaymentInfo{}, fmt.Errorf("request execution failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		var errResp errorResponse
-		err = json.NewDecoder(resp.Body).Decode(&errResp)
-		if err != nil {
-			return PaymentInfo{}, fmt.Errorf("decoding error failed: %w (status code %d)", err, resp.StatusCode)
-		}
-		return PaymentInfo{}, &ServerError{
-			StatusCode:   errResp.Code,
-			InternalCode: errResp.InternalCode,
-			Message:      errResp.DevMessage,
-			UserMessage:  errResp.UserMessage.LangRu,
-		}
-	}
+	request := makeReversePaymentRequest(creds.RegNum, payment)
 
 	var rawPaymentInfo paymentInfoResponse
-	err = json.NewDecoder(resp.Body).Decode(&rawPaymentInfo)
+	err = a.do(ctx, creds, OperationReversePayment, http.MethodPost, "/pos/payments/"+strconv.FormatInt(paymentId, 10)+"/reversals", &request, &rawPaymentInfo)
 	if err != nil {
-		return PaymentInfo{}, fmt.Errorf("decoding response failed: %w", err)
+		return PaymentInfo{}, err
 	}
 
 	paymentInfo, err := makePaymentInfoFromRaw(rawPaymentInfo)
 	if err != nil {
 		return PaymentInfo{}, fmt.Errorf("handling response failed: %w", err)
 	}
+	a.paymentReversed(ctx, paymentId, payment, paymentInfo)
 
 	return paymentInfo, nil
 }
@@ -1,17 +1,133 @@
 package oacquiring
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LangRu - русский язык сообщений об ошибках
+	LangRu = "ru"
+	// LangEn - английский язык сообщений об ошибках
+	LangEn = "en"
+)
+
+// maxErrorBodySize - максимальный размер тела ответа с ошибкой, сохраняемого в ServerError.Body
+const maxErrorBodySize = 64 << 10
 
 type (
-	// ServerError - ошибка сервера Оплати
+	// ServerError - ошибка сервера Оплати. Решение о повторе запроса принимается по Retryable (HTTP код ответа), а не
+	// по InternalCode.
+	//
+	// Если сервер вернул ответ не в формате json (например, HTML страницу шлюза или пустое тело), заполняются только
+	// HTTPStatus, StatusCode, Message, Header и Body.
 	ServerError struct {
-		StatusCode   string // Код ошибки
-		InternalCode string // Внутренний код ошибки
-		Message      string // Сообщение
-		UserMessage  string // Сообщение для пользователя
+		StatusCode    string      // Код ошибки
+		InternalCode  string      // Внутренний код ошибки
+		Message       string      // Сообщение
+		UserMessage   string      // Сообщение для пользователя на русском языке
+		UserMessageEn string      // Сообщение для пользователя на английском языке
+		HTTPStatus    int         // HTTP код ответа, например 400
+		Header        http.Header // Заголовки ответа
+		Body          []byte      // Тело ответа (не более 64 КБ)
 	}
 )
 
+// newServerError создает ServerError из ответа сервера с кодом, отличным от 200 OK
+func newServerError(resp *http.Response) *ServerError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	serverErr := &ServerError{
+		HTTPStatus: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	var errResp errorResponse
+	if len(bytes.TrimSpace(body)) == 0 || json.Unmarshal(body, &errResp) != nil {
+		serverErr.StatusCode = strconv.Itoa(resp.StatusCode)
+		serverErr.Message = fmt.Sprintf("unexpected response %q", http.StatusText(resp.StatusCode))
+		return serverErr
+	}
+
+	serverErr.StatusCode = errResp.Code
+	serverErr.InternalCode = errResp.InternalCode
+	serverErr.Message = errResp.DevMessage
+	serverErr.UserMessage = errResp.UserMessage.LangRu
+	serverErr.UserMessageEn = errResp.UserMessage.LangEn
+	if serverErr.StatusCode == "" {
+		serverErr.StatusCode = strconv.Itoa(resp.StatusCode)
+	}
+
+	return serverErr
+}
+
 func (s *ServerError) Error() string {
+	if s.InternalCode == "" {
+		return fmt.Sprintf("OPLATI error (HTTP %d): %s", s.HTTPStatus, s.Message)
+	}
 	return fmt.Sprintf("OPLATI error %s: %s", s.InternalCode, s.Message)
 }
+
+// UserMessageFor возвращает сообщение для пользователя на языке lang (LangRu или LangEn). Если сообщение на этом
+// языке отсутствует, возвращается сообщение на другом языке.
+func (s *ServerError) UserMessageFor(lang string) string {
+	if strings.EqualFold(lang, LangEn) {
+		if s.UserMessageEn != "" {
+			return s.UserMessageEn
+		}
+		return s.UserMessage
+	}
+
+	if s.UserMessage != "" {
+		return s.UserMessage
+	}
+	return s.UserMessageEn
+}
+
+// Code возвращает ErrorCode, соответствующий InternalCode. Для неизвестных кодов возвращает ErrUnknownServerError.
+func (s *ServerError) Code() *ErrorCode {
+	return LookupErrorCode(s.InternalCode)
+}
+
+// Is позволяет сравнивать ServerError с ErrorCode с помощью errors.Is
+func (s *ServerError) Is(target error) bool {
+	code, ok := target.(*ErrorCode)
+	if !ok {
+		return false
+	}
+	return s.Code() == code
+}
+
+// Retryable возвращает true, если запрос можно повторить позже: сервер ответил 429 Too Many Requests или 5xx. Код
+// ошибки (InternalCode) не учитывается.
+func (s *ServerError) Retryable() bool {
+	return isRetryableStatus(s.httpStatus())
+}
+
+// httpStatus возвращает HTTP код ответа. Если HTTPStatus не заполнен, используется StatusCode.
+func (s *ServerError) httpStatus() int {
+	if s.HTTPStatus != 0 {
+		return s.HTTPStatus
+	}
+	status, _ := strconv.Atoi(s.StatusCode)
+	return status
+}
+
+// RetryAfter возвращает паузу перед повтором запроса из заголовка ответа Retry-After либо 0, если заголовок
+// отсутствует
+func (s *ServerError) RetryAfter() time.Duration {
+	return parseRetryAfter(s.Header.Get("Retry-After"), time.Now())
+}
+
+// Temporary возвращает true, если ошибка временная. Совпадает с Retryable, реализован для совместимости с кодом,
+// проверяющим интерфейс interface{ Temporary() bool }.
+func (s *ServerError) Temporary() bool {
+	return s.Retryable()
+}
@@ -5,54 +5,113 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
+type (
+	// PaymentDecodeError - ошибка разбора одного платежа из списка платежей за смену, см.
+	// Client.GetPaymentsOnShiftLenient
+	PaymentDecodeError struct {
+		Index     int             // Позиция платежа в ответе сервера Оплати
+		PaymentId int64           // Идентификатор платежа, если его удалось определить
+		Raw       json.RawMessage // Исходные данные платежа
+		Err       error           // Причина ошибки
+	}
+)
+
+func (e *PaymentDecodeError) Error() string {
+	if e.PaymentId != 0 {
+		return fmt.Sprintf("payment %d (#%d) decoding failed: %v", e.PaymentId, e.Index, e.Err)
+	}
+	return fmt.Sprintf("payment #%d decoding failed: %v", e.Index, e.Err)
+}
+
+func (e *PaymentDecodeError) Unwrap() error {
+	return e.Err
+}
+
 // GetPaymentsOnShift - Получение списка платежей для сверки итогов по смене. Используется запрос GET /pos/paymentReports.
 //
 // В случае, если сервер вернул ответ отличный от 200 OK, возвращаемый error можно попробовать привести к *ServerError
-// для получения дополнительных данных об ошибке.
+// для получения дополнительных данных об ошибке. Если хотя бы один платеж не удалось разобрать, возвращается ошибка;
+// чтобы получить остальные платежи, используйте GetPaymentsOnShiftLenient.
 func (a *Client) GetPaymentsOnShift(ctx context.Context, shift string) ([]PaymentInfo, error) {
-	r, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseUrl+"/pos/paymentReports?shift="+shift, nil)
+	creds, err := a.getCredentials(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("request initialization failed: %w", err)
+		return nil, err
 	}
 
-	r.Header.Set("RegNum", a.cashboxRegNumber)
-	r.Header.Set("Password", a.cashboxPassword)
-
-	resp, err := a.httpClient.Do(r)
+	var rawPayments []paymentInfoResponse
+	err = a.do(ctx, creds, OperationGetPaymentsOnShift, http.MethodGet, "/pos/paymentReports?shift="+url.QueryEscape(shift), nil, &rawPayments)
 	if err != nil {
-		return nil, fmt.Errorf("request execution failed: %w", err)
+		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		var errResp errorResponse
-		err = json.NewDecoder(resp.Body).Decode(&errResp)
+	payments := make([]PaymentInfo, len(rawPayments))
+	for i, rawPayment := range rawPayments {
+		payments[i], err = makePaymentInfoFromRaw(rawPayment)
 		if err != nil {
-			return nil, fmt.Errorf("decoding error failed: %w (status code %d)", err, resp.StatusCode)
-		}
-		return nil, &ServerError{
-			StatusCode:   errResp.Code,
-			InternalCode: errResp.InternalCode,
-			Message:      errResp.DevMessage,
-			UserMessage:  errResp.UserMessage.LangRu,
+			return nil, fmt.Errorf("handling response failed: %w", err)
 		}
 	}
 
-	var rawPayments []paymentInfoResponse
-	err = json.NewDecoder(resp.Body).Decode(&rawPayments)
+	for _, payment := range payments {
+		a.paymentInfoReceived(ctx, OperationGetPaymentsOnShift, payment)
+	}
+
+	return payments, nil
+}
+
+// GetPaymentsOnShiftLenient - аналог GetPaymentsOnShift, который не прерывается на платежах, которые не удалось
+// разобрать. Возвращает успешно разобранные платежи и ошибки *PaymentDecodeError по остальным. error возвращается
+// только при ошибке запроса или если ответ сервера не является списком.
+func (a *Client) GetPaymentsOnShiftLenient(ctx context.Context, shift string) ([]PaymentInfo, []*PaymentDecodeError, error) {
+	creds, err := a.getCredentials(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("decoding response failed: %w", err)
+		return nil, nil, err
 	}
 
-	payments := make([]PaymentInfo, len(rawPayments))
-	for i, rawPayment := range rawPayments {
-		payments[i], err = makePaymentInfoFromRaw(rawPayment)
+	var rawPayments []json.RawMessage
+	err = a.do(ctx, creds, OperationGetPaymentsOnShift, http.MethodGet, "/pos/paymentReports?shift="+url.QueryEscape(shift), nil, &rawPayments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		payments     []PaymentInfo
+		decodeErrors []*PaymentDecodeError
+	)
+	for i, raw := range rawPayments {
+		payment, err := decodeShiftPayment(raw)
 		if err != nil {
-			return nil, fmt.Errorf("handling response failed: %w", err)
+			decodeErrors = append(decodeErrors, &PaymentDecodeError{Index: i, PaymentId: payment.Id, Raw: raw, Err: err})
+			continue
 		}
+		payments = append(payments, payment)
 	}
 
-	return payments, nil
+	for _, payment := range payments {
+		a.paymentInfoReceived(ctx, OperationGetPaymentsOnShift, payment)
+	}
+
+	return payments, decodeErrors, nil
+}
+
+// decodeShiftPayment разбирает один платеж из списка платежей за смену. При ошибке возвращаемый PaymentInfo содержит
+// только Id, если его удалось определить.
+func decodeShiftPayment(raw json.RawMessage) (PaymentInfo, error) {
+	var rawPayment paymentInfoResponse
+	if err := json.Unmarshal(raw, &rawPayment); err != nil {
+		var id struct {
+			PaymentId int64 `json:"paymentId"`
+		}
+		_ = json.Unmarshal(raw, &id)
+		return PaymentInfo{Id: id.PaymentId}, err
+	}
+
+	payment, err := makePaymentInfoFromRaw(rawPayment)
+	if err != nil {
+		return PaymentInfo{Id: rawPayment.PaymentId}, err
+	}
+	return payment, nil
 }
@@ -0,0 +1,109 @@
+package oacquiring
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ServerSignHeader - заголовок уведомления с подписью тела запроса
+const ServerSignHeader = "Server-Sign"
+
+var (
+	// ErrSignatureMissing - заголовок Server-Sign отсутствует или пуст
+	ErrSignatureMissing = errors.New("signature is missing")
+	// ErrSignatureEncoding - значение Server-Sign не является строкой base64
+	ErrSignatureEncoding = errors.New("signature is not valid base64")
+	// ErrSignatureMismatch - подпись не соответствует телу уведомления и публичному ключу
+	ErrSignatureMismatch = errors.New("signature does not match body")
+)
+
+// ParsePublicKey разбирает публичный RSA ключ в формате, в котором он выдается в личном кабинете Оплати.Бизнес
+// (base64 от DER в формате PKIX).
+func ParsePublicKey(publicKey string) (*rsa.PublicKey, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("public key base64 decoding failed: %w", err)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("public key parsing failed: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("provided public key is not RSA")
+	}
+
+	return rsaKey, nil
+}
+
+// EncodePublicKey кодирует публичный RSA ключ в формат, принимаемый ParsePublicKey и NewHTTPNotificationHandler
+func EncodePublicKey(publicKey *rsa.PublicKey) (string, error) {
+	rawKey, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("public key encoding failed: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(rawKey), nil
+}
+
+// VerifyNotification проверяет подпись signature (значение заголовка Server-Sign) тела уведомления body. Возвращает
+// ошибку, которую можно проверить с помощью errors.Is: ErrSignatureMissing, ErrSignatureEncoding или
+// ErrSignatureMismatch.
+func VerifyNotification(publicKey *rsa.PublicKey, body []byte, signature string) error {
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+
+	decodedSignature, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignatureEncoding, err)
+	}
+
+	sum := sha256.Sum256(body)
+
+	err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, sum[:], decodedSignature)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignatureMismatch, err)
+	}
+
+	return nil
+}
+
+// SignNotification возвращает подпись тела уведомления body в формате заголовка Server-Sign. Предназначена для
+// тестирования обработчиков уведомлений с собственным тестовым ключом.
+func SignNotification(privateKey *rsa.PrivateKey, body []byte) (string, error) {
+	sum := sha256.Sum256(body)
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("signing failed: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// ParseNotification разбирает тело уведомления от сервера Оплати. Подпись не проверяется, используйте
+// VerifyNotification.
+func ParseNotification(body []byte) (PaymentInfo, error) {
+	var rawPaymentInfo paymentInfoResponse
+	err := json.Unmarshal(body, &rawPaymentInfo)
+	if err != nil {
+		return PaymentInfo{}, fmt.Errorf("notification decoding failed: %w", err)
+	}
+
+	return makePaymentInfoFromRaw(rawPaymentInfo)
+}
+
+// MarshalNotification возвращает тело уведомления с данными платежа в формате сервера Оплати. Вместе с
+// SignNotification позволяет создавать тестовые уведомления.
+func MarshalNotification(payment PaymentInfo) ([]byte, error) {
+	return json.Marshal(makeRawFromPaymentInfo(payment))
+}
@@ -0,0 +1,358 @@
+// Package sweeper содержит Sweeper - фоновую проверку платежей, которые остаются в статусе
+// oacquiring.PaymentStatusInProgress в хранилище (например, если уведомление от сервера Оплати не было получено).
+// Sweeper периодически запрашивает статусы таких платежей через GetPaymentInfo, передает окончательные статусы тому же
+// обработчику, что и HTTP уведомления, и сообщает о платежах, не получивших окончательный статус за заданное время.
+//
+//	repo := repository.NewSQL(db, repository.Postgres)
+//	syncer := repository.NewSyncer(repo, func(err error) { log.Print(err) })
+//	handler := syncer.NotificationHandler(&Handler{})
+//	// ...
+//	s := sweeper.New(&oplatiClient, repo, handler,
+//		sweeper.WithInterval(time.Minute),
+//		sweeper.WithEscalation(time.Hour, func(ctx context.Context, payment repository.Payment) error {
+//			return alerts.Send(ctx, fmt.Sprintf("payment %d is stuck", payment.Id))
+//		}),
+//		sweeper.WithErrorHandler(func(err error) { log.Print(err) }))
+//	go s.Run(ctx)
+package sweeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	oacquiring "github.com/oplati-by/go-acquiring"
+	"github.com/oplati-by/go-acquiring/repository"
+)
+
+const (
+	defaultInterval        = time.Minute
+	defaultJitter          = 0.1
+	defaultMinAge          = time.Minute
+	defaultBatchSize       = 100
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+type (
+	// PaymentInfoGetter - источник статусов платежей, например *oacquiring.Client
+	PaymentInfoGetter interface {
+		GetPaymentInfo(ctx context.Context, paymentId int64) (oacquiring.PaymentInfo, error)
+	}
+
+	// Repository - хранилище платежей, используемое Sweeper. Реализуется repository.PaymentRepository.
+	Repository interface {
+		ListByStatus(ctx context.Context, status oacquiring.PaymentStatus, limit int) ([]repository.Payment, error)
+		ApplyInfo(ctx context.Context, info oacquiring.PaymentInfo) (bool, error)
+		Touch(ctx context.Context, paymentId int64) error
+	}
+
+	// EscalationFunc - функция, вызываемая для платежа, не получившего окончательный статус за время, указанное в
+	// WithEscalation. Если функция вернула ошибку, она будет вызвана повторно при следующей проверке.
+	EscalationFunc func(ctx context.Context, payment repository.Payment) error
+
+	// Sweeper периодически проверяет платежи хранилища в статусе oacquiring.PaymentStatusInProgress:
+	//  1. Запрашивает статус платежа через PaymentInfoGetter.GetPaymentInfo
+	//  2. Если статус окончательный, передает его обработчику уведомлений (как уведомление без тела и заголовков) и
+	//  после успешной обработки сохраняет в хранилище. Если обработчик вернул ошибку, платеж будет проверен повторно.
+	//  Обработчик не должен сохранять окончательный статус до успешной обработки (repository.Syncer.NotificationHandler
+	//  сохраняет его после вложенного обработчика)
+	//  3. Если статус не изменился, запрос статуса или обработка завершились ошибкой, обновляет время изменения платежа
+	//  в хранилище, чтобы при ограниченном размере партии платежи проверялись по очереди, а платежи с постоянными
+	//  ошибками не блокировали проверку остальных
+	//  4. Если платеж не получил окончательный статус за время, указанное в WithEscalation, один раз вызывает
+	//  EscalationFunc
+	//
+	// Поскольку обработчик может получить один и тот же статус и из уведомления, и от Sweeper, он должен быть
+	// идемпотентным (например, repository.Syncer.NotificationHandler). Для инициализации используйте New.
+	Sweeper struct {
+		client  PaymentInfoGetter
+		repo    Repository
+		handler oacquiring.PaymentNotificationHandler
+
+		interval        time.Duration
+		jitter          float64
+		minAge          time.Duration
+		batchSize       int
+		shutdownTimeout time.Duration
+		escalateAfter   time.Duration
+		escalate        EscalationFunc
+		onError         func(error)
+
+		mu        sync.Mutex
+		escalated map[int64]struct{}
+	}
+
+	// Opt - дополнительные параметры Sweeper
+	Opt func(*Sweeper)
+)
+
+// WithInterval - интервал между проверками. По умолчанию 1 минута
+func WithInterval(interval time.Duration) Opt {
+	return func(s *Sweeper) {
+		s.interval = interval
+	}
+}
+
+// WithJitter - случайное отклонение интервала между проверками в долях интервала, чтобы несколько экземпляров
+// приложения не обращались к серверу Оплати одновременно. Например, при 0.1 и интервале 1 минута пауза составит от 54
+// до 66 секунд. По умолчанию 0.1, 0 - без отклонения
+func WithJitter(fraction float64) Opt {
+	return func(s *Sweeper) {
+		s.jitter = fraction
+	}
+}
+
+// WithMinAge - минимальный возраст платежа для проверки. Более новые платежи пропускаются, т.к. уведомление по ним
+// еще может прийти. По умолчанию 1 минута
+func WithMinAge(age time.Duration) Opt {
+	return func(s *Sweeper) {
+		s.minAge = age
+	}
+}
+
+// WithBatchSize - максимальное количество платежей, проверяемых за одну проверку. По умолчанию 100
+func WithBatchSize(size int) Opt {
+	return func(s *Sweeper) {
+		s.batchSize = size
+	}
+}
+
+// WithShutdownTimeout - время, в течение которого после отмены контекста Run завершается обработка текущего платежа.
+// По умолчанию 10 секунд
+func WithShutdownTimeout(timeout time.Duration) Opt {
+	return func(s *Sweeper) {
+		s.shutdownTimeout = timeout
+	}
+}
+
+// WithEscalation - функция escalate вызывается один раз для каждого платежа, не получившего окончательный статус за
+// время after с момента создания
+func WithEscalation(after time.Duration, escalate EscalationFunc) Opt {
+	return func(s *Sweeper) {
+		s.escalateAfter = after
+		s.escalate = escalate
+	}
+}
+
+// WithErrorHandler - функция, вызываемая при ошибках получения списка платежей, запроса статуса, обработки и
+// сохранения платежа
+func WithErrorHandler(onError func(error)) Opt {
+	return func(s *Sweeper) {
+		s.onError = onError
+	}
+}
+
+// New возвращает новый Sweeper.
+//   - client - источник статусов платежей, например *oacquiring.Client
+//   - repo - хранилище платежей
+//   - handler - обработчик окончательных статусов, обычно тот же, что передан в oacquiring.NewHTTPNotificationHandler
+//   - opts - Дополнительные настройки: WithInterval, WithJitter, WithMinAge, WithBatchSize, WithShutdownTimeout,
+//     WithEscalation, WithErrorHandler
+func New(client PaymentInfoGetter, repo Repository, handler oacquiring.PaymentNotificationHandler, opts ...Opt) *Sweeper {
+	s := &Sweeper{
+		client:          client,
+		repo:            repo,
+		handler:         handler,
+		interval:        defaultInterval,
+		jitter:          defaultJitter,
+		minAge:          defaultMinAge,
+		batchSize:       defaultBatchSize,
+		shutdownTimeout: defaultShutdownTimeout,
+		escalated:       make(map[int64]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.interval <= 0 {
+		s.interval = defaultInterval
+	}
+	if s.jitter < 0 || s.jitter >= 1 {
+		s.jitter = defaultJitter
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultBatchSize
+	}
+
+	return s
+}
+
+// Run выполняет проверки до отмены ctx: первая проверка выполняется сразу, следующие - через интервал со случайным
+// отклонением. Ошибки не прерывают работу и передаются в функцию из WithErrorHandler.
+//
+// После отмены ctx новые платежи не проверяются, а обработка текущего платежа продолжается не дольше времени из
+// WithShutdownTimeout, чтобы не прерывать обработчик на середине. Возвращает ctx.Err().
+func (s *Sweeper) Run(ctx context.Context) error {
+	for {
+		_, err := s.SweepOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.error(err)
+		}
+
+		timer := time.NewTimer(s.nextDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// SweepOnce выполняет одну проверку и возвращает количество платежей, получивших окончательный статус. Ошибки
+// отдельных платежей передаются в функцию из WithErrorHandler; возвращается только ошибка получения списка платежей
+// или ошибка ctx, если проверка прервана.
+func (s *Sweeper) SweepOnce(ctx context.Context) (int, error) {
+	payments, err := s.repo.ListByStatus(ctx, oacquiring.PaymentStatusInProgress, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("pending payments listing failed: %w", err)
+	}
+
+	now := time.Now()
+	pending := make(map[int64]struct{}, len(payments))
+	resolved := 0
+	for _, payment := range payments {
+		pending[payment.Id] = struct{}{}
+		if age(payment, now) < s.minAge {
+			continue
+		}
+		if ctx.Err() != nil {
+			return resolved, ctx.Err()
+		}
+
+		if s.sweep(ctx, payment, now) {
+			resolved++
+		}
+	}
+
+	if len(payments) < s.batchSize {
+		s.forgetEscalated(pending)
+	}
+
+	return resolved, nil
+}
+
+// sweep проверяет один платеж и возвращает true, если платеж получил окончательный статус
+func (s *Sweeper) sweep(ctx context.Context, payment repository.Payment, now time.Time) bool {
+	ctx, cancel := s.paymentContext(ctx)
+	defer cancel()
+
+	info, err := s.client.GetPaymentInfo(ctx, payment.Id)
+	if err != nil {
+		s.error(fmt.Errorf("payment %d status request failed: %w", payment.Id, err))
+		s.touch(ctx, payment.Id)
+		s.escalateIfStuck(ctx, payment, now)
+		return false
+	}
+
+	if !info.Status.IsFinal() {
+		if _, err = s.repo.ApplyInfo(ctx, info); err != nil {
+			s.error(fmt.Errorf("payment %d saving failed: %w", payment.Id, err))
+		}
+		s.escalateIfStuck(ctx, payment, now)
+		return false
+	}
+
+	err = oacquiring.DeliverNotification(ctx, s.handler, oacquiring.Notification{Payment: info})
+	if err != nil {
+		s.error(fmt.Errorf("payment %d handling failed: %w", payment.Id, err))
+		s.touch(ctx, payment.Id)
+		return false
+	}
+
+	if _, err = s.repo.ApplyInfo(ctx, info); err != nil {
+		s.error(fmt.Errorf("payment %d saving failed: %w", payment.Id, err))
+	}
+
+	return true
+}
+
+// touch отмечает попытку проверки платежа, перемещая его в конец очереди ListByStatus
+func (s *Sweeper) touch(ctx context.Context, paymentId int64) {
+	if err := s.repo.Touch(ctx, paymentId); err != nil {
+		s.error(fmt.Errorf("payment %d saving failed: %w", paymentId, err))
+	}
+}
+
+func (s *Sweeper) escalateIfStuck(ctx context.Context, payment repository.Payment, now time.Time) {
+	if s.escalate == nil || age(payment, now) < s.escalateAfter {
+		return
+	}
+
+	s.mu.Lock()
+	_, done := s.escalated[payment.Id]
+	s.mu.Unlock()
+	if done {
+		return
+	}
+
+	if err := s.escalate(ctx, payment); err != nil {
+		s.error(fmt.Errorf("payment %d escalation failed: %w", payment.Id, err))
+		return
+	}
+
+	s.mu.Lock()
+	s.escalated[payment.Id] = struct{}{}
+	s.mu.Unlock()
+}
+
+// forgetEscalated удаляет из списка эскалированных платежи, которых больше нет среди незавершенных
+func (s *Sweeper) forgetEscalated(pending map[int64]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.escalated {
+		if _, ok := pending[id]; !ok {
+			delete(s.escalated, id)
+		}
+	}
+}
+
+// paymentContext возвращает контекст обработки платежа, который отменяется не сразу после отмены ctx, а через
+// shutdownTimeout
+func (s *Sweeper) paymentContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	paymentCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(s.shutdownTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			cancel()
+		case <-paymentCtx.Done():
+		}
+	})
+
+	return paymentCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// nextDelay возвращает интервал до следующей проверки со случайным отклонением
+func (s *Sweeper) nextDelay() time.Duration {
+	if s.jitter == 0 {
+		return s.interval
+	}
+	return time.Duration(float64(s.interval) * (1 + s.jitter*(2*rand.Float64()-1)))
+}
+
+func (s *Sweeper) error(err error) {
+	if s.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
+
+// age возвращает возраст платежа: время с момента создания в Оплати или, если оно неизвестно, с момента сохранения в
+// хранилище
+func age(payment repository.Payment, now time.Time) time.Duration {
+	created := payment.CreatedDate
+	if created.IsZero() {
+		created = payment.CreatedAt
+	}
+	return now.Sub(created)
+}
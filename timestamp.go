@@ -0,0 +1,66 @@
+package oacquiring
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinskLocation - часовой пояс Europe/Minsk, в котором интерпретируются даты платежей без указания часового пояса.
+// Если база часовых поясов недоступна, используется фиксированное смещение UTC+3 (действует с 2011 года).
+var MinskLocation = loadMinskLocation()
+
+// timestampLayouts - форматы дат, которые встречаются в ответах и уведомлениях сервера Оплати. Форматы без часового
+// пояса интерпретируются в MinskLocation.
+var timestampLayouts = []struct {
+	layout string
+	zoned  bool
+}{
+	{time.RFC3339Nano, true},
+	{"2006-01-02T15:04:05.999999999Z0700", true},
+	{"2006-01-02T15:04:05.999999999Z07", true},
+	{"2006-01-02T15:04:05.999999999", false},
+	{"2006-01-02 15:04:05.999999999Z07:00", true},
+	{"2006-01-02 15:04:05.999999999", false},
+}
+
+func loadMinskLocation() *time.Location {
+	loc, err := time.LoadLocation("Europe/Minsk")
+	if err != nil {
+		return time.FixedZone("Europe/Minsk", 3*60*60)
+	}
+	return loc
+}
+
+// parseTimestamp разбирает дату платежа. Для пустого значения (в т.ч. null) возвращается нулевое время.
+func parseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "null" {
+		return time.Time{}, nil
+	}
+
+	for _, l := range timestampLayouts {
+		var (
+			t   time.Time
+			err error
+		)
+		if l.zoned {
+			t, err = time.Parse(l.layout, value)
+		} else {
+			t, err = time.ParseInLocation(l.layout, value, MinskLocation)
+		}
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time format %q", value)
+}
+
+// formatTimestamp возвращает дату платежа в формате RFC 3339 либо пустую строку для нулевого времени
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}